@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+	"github.com/interpretive-systems/diffium/internal/diffview"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+	"github.com/interpretive-systems/diffium/internal/report"
+	"github.com/interpretive-systems/diffium/internal/tui"
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+)
+
+func newDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff [paths...]",
+		Short: "Print the diff of changed files without the TUI",
+		Long:  "Print the side-by-side (or inline) diff of changed files to stdout, for CI logs and scripts.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoPath := mustGetStringFlag(cmd.Root(), "repo")
+			root, err := gitx.RepoRoot(repoPath)
+			if err != nil {
+				return fmt.Errorf("not a git repo: %w", err)
+			}
+			switch c := mustGetStringFlag(cmd, "color"); c {
+			case "auto":
+				// lipgloss detects the terminal on stdout
+			case "always":
+				lipgloss.SetColorProfile(termenv.TrueColor)
+			case "never":
+				lipgloss.SetColorProfile(termenv.Ascii)
+			default:
+				return fmt.Errorf("invalid --color %q (want auto, always or never)", c)
+			}
+			opts := diffOptions{}
+			opts.Staged, _ = cmd.Flags().GetBool("staged")
+			opts.Inline, _ = cmd.Flags().GetBool("inline")
+			opts.LineNumbers, _ = cmd.Flags().GetBool("line-numbers")
+			opts.JSON, _ = cmd.Flags().GetBool("json")
+			opts.Width, _ = cmd.Flags().GetInt("width")
+			if opts.Width <= 0 {
+				opts.Width = outputWidth()
+			}
+			return printDiff(cmd.OutOrStdout(), root, args, opts)
+		},
+	}
+	cmd.Flags().Bool("staged", false, "Show staged changes instead of the working tree")
+	cmd.Flags().Bool("inline", false, "Print an inline diff instead of side by side")
+	cmd.Flags().Bool("line-numbers", false, "Show line numbers")
+	cmd.Flags().Int("width", 0, "Output width in columns (default: terminal width, $COLUMNS, or 120)")
+	cmd.Flags().String("color", "auto", "Colorize output: auto, always or never")
+	cmd.Flags().Bool("json", false, "Print files, hunks and rows as JSON (see the README for the schema)")
+	return cmd
+}
+
+// outputWidth is the terminal width of stdout, falling back to $COLUMNS and
+// then 120 columns.
+func outputWidth() int {
+	if fd := os.Stdout.Fd(); term.IsTerminal(fd) {
+		if w, _, err := term.GetSize(fd); err == nil && w > 0 {
+			return w
+		}
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 120
+}
+
+// diffOptions configures printDiff.
+type diffOptions struct {
+	// Staged prints the staged changes instead of the working tree's.
+	Staged bool
+	// Inline prints one column instead of side by side.
+	Inline bool
+	// Width is the output width in columns.
+	Width int
+	// LineNumbers adds the line-number gutter.
+	LineNumbers bool
+	// JSON prints a report.Diff instead of rendering.
+	JSON bool
+}
+
+// printDiff writes the diffs of the changed files under paths (all changed
+// files when empty) to w, rendered like the watch diff pane with long lines
+// wrapped. Paths are relative to the current directory. Colors follow
+// lipgloss' color profile.
+func printDiff(w io.Writer, repoRoot string, paths []string, opts diffOptions) error {
+	all, err := gitx.ChangedFiles(repoRoot)
+	if err != nil {
+		return err
+	}
+	// the files the watch diff pane lists in the same mode
+	var files []gitx.FileChange
+	for _, f := range all {
+		if (opts.Staged && f.Staged) || (!opts.Staged && (f.Unstaged || f.Untracked)) {
+			files = append(files, f)
+		}
+	}
+	if files, err = filterPaths(repoRoot, files, paths); err != nil {
+		return err
+	}
+	theme := tui.LoadTheme(repoRoot)
+	width := max(opts.Width, 24)
+	out := report.Diff{Version: report.Version, Repo: repoRoot, Staged: opts.Staged, Files: []report.FileDiff{}}
+	for i, f := range files {
+		var rows []diffview.Row
+		if !f.Binary {
+			d, err := gitx.DiffFile(repoRoot, f, opts.Staged)
+			if err != nil {
+				return err
+			}
+			rows = diffview.BuildRowsFromUnified(d)
+		}
+		if opts.JSON {
+			out.Files = append(out.Files, report.NewFileDiff(f, rows))
+			continue
+		}
+		o := tui.RenderOptions{SideBySide: !opts.Inline, Wrap: true, LineNumbers: opts.LineNumbers}
+		if !theme.NoSyntax {
+			o.SynOld, o.SynNew = tui.HighlightChange(repoRoot, f, opts.Staged)
+		}
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		title := fmt.Sprintf("%s %s", tui.StatusLabel(f), tui.DisplayPath(f))
+		fmt.Fprintln(w, lipgloss.NewStyle().Bold(true).Render(title))
+		fmt.Fprintln(w, theme.DividerText(strings.Repeat("─", width)))
+		lines, _ := tui.RenderDiff(theme, f, rows, width, o)
+		for _, l := range lines {
+			if _, err := fmt.Fprintln(w, strings.TrimRight(l, " ")); err != nil {
+				return err
+			}
+		}
+	}
+	if opts.JSON {
+		return writeJSON(w, out)
+	}
+	return nil
+}
+
+// filterPaths keeps the files at or below any of paths.
+func filterPaths(repoRoot string, files []gitx.FileChange, paths []string) ([]gitx.FileChange, error) {
+	if len(paths) == 0 {
+		return files, nil
+	}
+	root, err := filepath.EvalSymlinks(repoRoot)
+	if err != nil {
+		root = repoRoot
+	}
+	var prefixes []string
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		if real, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = real
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			return nil, fmt.Errorf("%s is outside the repository", p)
+		}
+		prefixes = append(prefixes, filepath.ToSlash(rel))
+	}
+	var out []gitx.FileChange
+	for _, f := range files {
+		for _, p := range prefixes {
+			if p == "." || f.Path == p || strings.HasPrefix(f.Path, p+"/") || f.OldPath == p {
+				out = append(out, f)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestPrintDiff(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo changed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	if err := printDiff(&buf, dir, []string{filepath.Join(dir, "a.txt")}, diffOptions{Width: 60, Inline: true}); err != nil {
+		t.Fatal(err)
+	}
+	plain := ansi.Strip(buf.String())
+	for _, want := range []string{"M a.txt", "- two", "+ two changed"} {
+		if !strings.Contains(plain, want) {
+			t.Fatalf("expected %q in output:\n%s", want, plain)
+		}
+	}
+	if strings.Contains(plain, "b.txt") {
+		t.Fatalf("expected only the requested path:\n%s", plain)
+	}
+}
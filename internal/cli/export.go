@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/interpretive-systems/diffium/internal/diffview"
+	"github.com/interpretive-systems/diffium/internal/export"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+	"github.com/interpretive-systems/diffium/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+func newExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [range]",
+		Short: "Export the changes as a single HTML page",
+		Long:  "Export all changed files (HEAD against the working tree), or the files changed in a commit range such as main...feature, as one self-contained HTML page.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoPath := mustGetStringFlag(cmd.Root(), "repo")
+			root, err := gitx.RepoRoot(repoPath)
+			if err != nil {
+				return fmt.Errorf("not a git repo: %w", err)
+			}
+			if format := mustGetStringFlag(cmd, "format"); format != "html" {
+				return fmt.Errorf("unsupported --format %q (want html)", format)
+			}
+			var r gitx.Range
+			if len(args) == 1 {
+				if r, err = gitx.ParseRange(root, args[0]); err != nil {
+					return err
+				}
+			}
+			out := mustGetStringFlag(cmd, "output")
+			if out == "" || out == "-" {
+				return exportHTML(cmd.OutOrStdout(), root, r)
+			}
+			f, err := os.Create(out)
+			if err != nil {
+				return err
+			}
+			if err := exportHTML(f, root, r); err != nil {
+				f.Close()
+				return err
+			}
+			// a failed close can mean the page was not fully written
+			return f.Close()
+		},
+	}
+	cmd.Flags().String("format", "html", "Output format (html)")
+	cmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	return cmd
+}
+
+// exportHTML writes a self-contained HTML review of every changed file
+// (HEAD against the working tree), or of the files in r when it is set.
+func exportHTML(w io.Writer, repoRoot string, r gitx.Range) error {
+	var files []gitx.FileChange
+	var err error
+	subtitle := "HEAD vs working tree"
+	if r.Base != "" {
+		files, err = gitx.RangeFiles(repoRoot, r)
+		subtitle = r.Label
+	} else {
+		files, err = gitx.ChangedFiles(repoRoot)
+	}
+	if err != nil {
+		return err
+	}
+	theme := tui.LoadTheme(repoRoot)
+	p := export.Page{
+		Title:     "diffium review — " + filepath.Base(repoRoot),
+		Subtitle:  subtitle,
+		Generated: time.Now(),
+		Colors: export.Colors{
+			Add: theme.AddColor, Del: theme.DelColor,
+			AddEmph: theme.AddEmphColor, DelEmph: theme.DelEmphColor,
+			Divider: theme.DividerColor, Meta: theme.MetaColor,
+		},
+	}
+	for _, f := range files {
+		ef := export.File{Path: f.Path, Name: tui.DisplayPath(f), Status: tui.StatusLabel(f), Note: tui.RenameHeader(f)}
+		if f.Binary {
+			ef.Note = "Binary file; no text diff"
+		} else {
+			var d string
+			if r.Base != "" {
+				d, err = gitx.DiffRange(repoRoot, r, f)
+			} else {
+				d, err = gitx.DiffFile(repoRoot, f, false)
+			}
+			if err != nil {
+				ef.Note = "Error: " + err.Error()
+			}
+			ef.Rows = diffview.BuildRowsFromUnified(d)
+		}
+		p.Files = append(p.Files, ef)
+	}
+	return export.HTML(w, p)
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/interpretive-systems/diffium/internal/gitx"
+	"github.com/interpretive-systems/diffium/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+func newReviewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "review <range>",
+		Short: "Review the files changed in a commit range",
+		Long:  "Review the files changed in a commit range, e.g. main...agent/feature, HEAD~3..HEAD, or just main (same as main...HEAD).",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoPath := mustGetStringFlag(cmd.Root(), "repo")
+			root, err := gitx.RepoRoot(repoPath)
+			if err != nil {
+				return fmt.Errorf("not a git repo: %w", err)
+			}
+			r, err := gitx.ParseRange(root, args[0])
+			if err != nil {
+				return err
+			}
+			poll, _ := cmd.Flags().GetBool("poll")
+			return tui.Run(root, tui.Options{Poll: poll, Range: r})
+		},
+	}
+	cmd.Flags().Bool("poll", false, "Refresh every second instead of watching the filesystem")
+	return cmd
+}
@@ -18,6 +18,13 @@ func Execute() error {
 
 	// Add subcommands
 	root.AddCommand(newWatchCmd())
+	root.AddCommand(newReviewCmd())
+	root.AddCommand(newDiffCmd())
+	root.AddCommand(newStatusCmd())
+	root.AddCommand(newExportCmd())
+	root.AddCommand(newServeCmd())
+	root.AddCommand(newSnapshotCmd())
+	root.AddCommand(newUndoCmd())
 
 	if err := root.Execute(); err != nil {
 		return fmt.Errorf("execute: %w", err)
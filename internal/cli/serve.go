@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/interpretive-systems/diffium/internal/gitx"
+	"github.com/interpretive-systems/diffium/internal/server"
+	"github.com/spf13/cobra"
+)
+
+func newServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve JSON-RPC for editor integrations",
+		Long:  "Serve JSON-RPC 2.0 (one JSON message per line) on stdin/stdout with --stdio, or on a Unix socket with --socket. See the README for the methods.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoPath := mustGetStringFlag(cmd.Root(), "repo")
+			root, err := gitx.RepoRoot(repoPath)
+			if err != nil {
+				return fmt.Errorf("not a git repo: %w", err)
+			}
+			opts := server.Options{}
+			opts.Poll, _ = cmd.Flags().GetBool("poll")
+			stdio, _ := cmd.Flags().GetBool("stdio")
+			socket := mustGetStringFlag(cmd, "socket")
+			switch {
+			case stdio && socket != "":
+				return fmt.Errorf("use either --stdio or --socket")
+			case stdio:
+				return server.Serve(root, os.Stdin, os.Stdout, opts)
+			case socket != "":
+				return server.ListenUnix(root, socket, opts)
+			}
+			return fmt.Errorf("--stdio or --socket is required")
+		},
+	}
+	cmd.Flags().Bool("stdio", false, "Serve on stdin/stdout")
+	cmd.Flags().String("socket", "", "Serve on a Unix socket at this path")
+	cmd.Flags().Bool("poll", false, "Check for changes every second instead of watching the filesystem")
+	return cmd
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/interpretive-systems/diffium/internal/gitx"
+	"github.com/spf13/cobra"
+)
+
+func newSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Record the working tree for the since-snapshot diff mode",
+		Long:  "Record the working tree, including untracked files, under " + gitx.SnapshotRef + ". The TUI's since-snapshot mode (t) then shows only what changed after this point. The index is not touched.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoPath := mustGetStringFlag(cmd.Root(), "repo")
+			root, err := gitx.RepoRoot(repoPath)
+			if err != nil {
+				return fmt.Errorf("not a git repo: %w", err)
+			}
+			s, err := gitx.TakeSnapshot(root)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "snapshot %.7s at %s\n", s.ID, s.Time.Format("15:04:05"))
+			return nil
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/interpretive-systems/diffium/internal/gitx"
+	"github.com/interpretive-systems/diffium/internal/report"
+	"github.com/interpretive-systems/diffium/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+func newStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "List changed files",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoPath := mustGetStringFlag(cmd.Root(), "repo")
+			root, err := gitx.RepoRoot(repoPath)
+			if err != nil {
+				return fmt.Errorf("not a git repo: %w", err)
+			}
+			asJSON, _ := cmd.Flags().GetBool("json")
+			return printStatus(cmd.OutOrStdout(), root, asJSON)
+		},
+	}
+	cmd.Flags().Bool("json", false, "Print the files and their status flags as JSON (see the README for the schema)")
+	return cmd
+}
+
+// printStatus lists all changed files with their status letters as shown in
+// the file pane, or as a report.Status when asJSON is set.
+func printStatus(w io.Writer, repoRoot string, asJSON bool) error {
+	files, err := gitx.ChangedFiles(repoRoot)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		out := report.Status{Version: report.Version, Repo: repoRoot, Files: []report.File{}}
+		out.Branch, _ = gitx.CurrentBranch(repoRoot)
+		for _, f := range files {
+			out.Files = append(out.Files, report.NewFile(f))
+		}
+		return writeJSON(w, out)
+	}
+	for _, f := range files {
+		if _, err := fmt.Fprintf(w, "%-3s %s\n", tui.StatusLabel(f), tui.DisplayPath(f)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
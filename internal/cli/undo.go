@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/interpretive-systems/diffium/internal/gitx"
+	"github.com/spf13/cobra"
+)
+
+func newUndoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Reverse the last reset/clean, uncommit or checkout made in the TUI",
+		Long:  "Reverse the last destructive wizard operation (reset/clean, uncommit or branch checkout) using the state saved before it ran. Repeat to go further back.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoPath := mustGetStringFlag(cmd.Root(), "repo")
+			root, err := gitx.RepoRoot(repoPath)
+			if err != nil {
+				return fmt.Errorf("not a git repo: %w", err)
+			}
+			if dry, _ := cmd.Flags().GetBool("dry-run"); dry {
+				op, ok, err := gitx.LastUndo(root)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return gitx.ErrNothingToUndo
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "would undo %s from %s\n", op, op.Time.Format("2006-01-02 15:04:05"))
+				return nil
+			}
+			noCheckpoints, _ := cmd.Flags().GetBool("no-checkpoints")
+			op, err := gitx.Undo(root, !noCheckpoints)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "undid %s from %s\n", op, op.Time.Format("2006-01-02 15:04:05"))
+			return nil
+		},
+	}
+	cmd.Flags().BoolP("dry-run", "n", false, "Only print the operation that would be undone")
+	cmd.Flags().Bool("no-checkpoints", false, "Do not checkpoint the working tree before undoing a reset")
+	return cmd
+}
@@ -18,8 +18,25 @@ func newWatchCmd() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("not a git repo: %w", err)
 			}
-			return tui.Run(root)
+			opts := tui.Options{}
+			opts.Poll, _ = cmd.Flags().GetBool("poll")
+			opts.NoCheckpoints, _ = cmd.Flags().GetBool("no-checkpoints")
+			base, _ := cmd.Flags().GetString("base")
+			head, _ := cmd.Flags().GetString("head")
+			if head != "" && base == "" {
+				return fmt.Errorf("--head requires --base")
+			}
+			if base != "" {
+				if opts.Range, err = gitx.NewRange(root, base, head); err != nil {
+					return err
+				}
+			}
+			return tui.Run(root, opts)
 		},
 	}
+	cmd.Flags().Bool("poll", false, "Refresh every second instead of watching the filesystem")
+	cmd.Flags().Bool("no-checkpoints", false, "Do not record checkpoints of the working tree as it changes")
+	cmd.Flags().String("base", "", "Compare against this revision (or a range like main...feature) instead of HEAD")
+	cmd.Flags().String("head", "", "Revision to compare --base with (default: the working tree)")
 	return cmd
 }
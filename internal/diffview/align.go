@@ -0,0 +1,147 @@
+package diffview
+
+// Pairing thresholds for alignChanges.
+const (
+	// minPairSimilarity is the share of words two lines must have in common
+	// to be shown side by side as an edit rather than a delete and an add.
+	minPairSimilarity = 0.5
+	// maxAlignCells bounds the alignment table; larger change blocks fall
+	// back to pairing lines in order.
+	maxAlignCells = 40000
+	// maxAlignWork bounds the word comparisons spent scoring all pairs of a
+	// block; a block that needs more also falls back to pairing in order.
+	maxAlignWork = 4000000
+)
+
+// alignChanges turns one block of consecutive deletions and additions into
+// rows. Lines are paired as RowReplace where they are similar enough, in an
+// order-preserving alignment that maximizes total similarity; everything
+// else stays a pure RowDel or RowAdd. Both sides keep their original order,
+// so patches rebuilt from the rows still apply.
+func alignChanges(dels, adds []Row) []Row {
+	n, m := len(dels), len(adds)
+	if n == 0 || m == 0 {
+		return append(append([]Row(nil), dels...), adds...)
+	}
+	if n*m > maxAlignCells {
+		return pairInOrder(dels, adds)
+	}
+	a := make([][]string, n)
+	for i, r := range dels {
+		a[i] = words(r.Left)
+	}
+	b := make([][]string, m)
+	for j, r := range adds {
+		b[j] = words(r.Right)
+	}
+	// score[i][j] is the best total similarity aligning dels[i:] with adds[j:]
+	score := make([][]float64, n+1)
+	sim := make([][]float64, n)
+	for i := range score {
+		score[i] = make([]float64, m+1)
+	}
+	work := 0
+	for i := n - 1; i >= 0; i-- {
+		sim[i] = make([]float64, m)
+		for j := m - 1; j >= 0; j-- {
+			best := max(score[i+1][j], score[i][j+1])
+			s := 0.0
+			switch {
+			case dels[i].Left == adds[j].Right:
+				s = 1
+			case mayPair(len(a[i]), len(b[j])):
+				if work += len(a[i]) * len(b[j]); work > maxAlignWork {
+					return pairInOrder(dels, adds)
+				}
+				s = similarity(a[i], b[j])
+			}
+			if s >= minPairSimilarity {
+				sim[i][j] = s
+				best = max(best, score[i+1][j+1]+s)
+			}
+			score[i][j] = best
+		}
+	}
+	rows := make([]Row, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case sim[i][j] > 0 && score[i][j] == score[i+1][j+1]+sim[i][j]:
+			r := dels[i]
+			r.Kind = RowReplace
+			r.Right = adds[j].Right
+			r.RightLine = adds[j].RightLine
+			r.RightNoEOL = adds[j].RightNoEOL
+			rows = append(rows, r)
+			i++
+			j++
+		case score[i][j] == score[i+1][j]:
+			rows = append(rows, dels[i])
+			i++
+		default:
+			rows = append(rows, adds[j])
+			j++
+		}
+	}
+	rows = append(rows, dels[i:]...)
+	return append(rows, adds[j:]...)
+}
+
+// pairInOrder pairs the k-th deletion with the k-th addition.
+func pairInOrder(dels, adds []Row) []Row {
+	rows := make([]Row, 0, max(len(dels), len(adds)))
+	for k := 0; k < len(dels) || k < len(adds); k++ {
+		switch {
+		case k >= len(adds):
+			rows = append(rows, dels[k])
+		case k >= len(dels):
+			rows = append(rows, adds[k])
+		default:
+			r := dels[k]
+			r.Kind = RowReplace
+			r.Right = adds[k].Right
+			r.RightLine = adds[k].RightLine
+			r.RightNoEOL = adds[k].RightNoEOL
+			rows = append(rows, r)
+		}
+	}
+	return rows
+}
+
+// words returns the non-whitespace tokens of s.
+func words(s string) []string {
+	var out []string
+	for _, t := range tokenize(s) {
+		if !isSpaceToken(t.text) {
+			out = append(out, t.text)
+		}
+	}
+	return out
+}
+
+// mayPair reports whether lines of na and nb words can reach
+// minPairSimilarity: they have at most the shorter line's words in common.
+func mayPair(na, nb int) bool {
+	return na > 0 && nb > 0 && 2*float64(min(na, nb))/float64(na+nb) >= minPairSimilarity
+}
+
+// similarity scores the words of two lines from 0 (nothing in common) to 1
+// (same words).
+func similarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 || len(a)*len(b) > maxIntraLineCells {
+		return 0
+	}
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return 2 * float64(prev[len(b)]) / float64(len(a)+len(b))
+}
@@ -0,0 +1,190 @@
+package diffview
+
+import "strings"
+
+// ConflictSide selects which version of a conflict block to keep.
+type ConflictSide int
+
+const (
+	TakeOurs ConflictSide = iota
+	TakeTheirs
+)
+
+// ConflictBlock is one `<<<<<<< ... >>>>>>>` region of a conflicted file.
+// Lines keep their line endings so the file can be written back unchanged.
+type ConflictBlock struct {
+	Ours, Base, Theirs []string
+	// Marker lines as found in the file. BaseMarker is empty unless the file
+	// was written with diff3/zdiff3 conflict style.
+	OursMarker, BaseMarker, SepMarker, TheirsMarker string
+	// BaseKnown reports whether Base holds the common ancestor, either from
+	// the file itself or from BorrowBase.
+	BaseKnown bool
+}
+
+// OursLabel returns the label after the <<<<<<< marker (e.g. "HEAD").
+func (b *ConflictBlock) OursLabel() string { return markerLabel(b.OursMarker) }
+
+// TheirsLabel returns the label after the >>>>>>> marker (e.g. a branch name).
+func (b *ConflictBlock) TheirsLabel() string { return markerLabel(b.TheirsMarker) }
+
+// ConflictSegment is either plain text shared by both sides or a block.
+type ConflictSegment struct {
+	Lines []string // set when Block is nil
+	Block *ConflictBlock
+}
+
+// ConflictFile is a working tree file split at its conflict markers.
+type ConflictFile struct {
+	Segments []ConflictSegment
+}
+
+// ParseConflicts splits file content at git's conflict markers. An
+// unterminated block is kept as plain text.
+func ParseConflicts(content string) *ConflictFile {
+	f := &ConflictFile{}
+	var text []string
+	flushText := func() {
+		if len(text) > 0 {
+			f.Segments = append(f.Segments, ConflictSegment{Lines: text})
+			text = nil
+		}
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if n := len(lines); n > 0 && lines[n-1] == "" {
+		lines = lines[:n-1]
+	}
+	for i := 0; i < len(lines); i++ {
+		if !isMarker(lines[i], '<') {
+			text = append(text, lines[i])
+			continue
+		}
+		b, end := parseBlock(lines, i)
+		if b == nil {
+			text = append(text, lines[i])
+			continue
+		}
+		flushText()
+		f.Segments = append(f.Segments, ConflictSegment{Block: b})
+		i = end
+	}
+	flushText()
+	return f
+}
+
+// parseBlock parses the block opening at lines[start] and returns it with
+// the index of its closing marker, or nil if the block is not terminated.
+func parseBlock(lines []string, start int) (*ConflictBlock, int) {
+	b := &ConflictBlock{OursMarker: lines[start]}
+	section := &b.Ours
+	for i := start + 1; i < len(lines); i++ {
+		l := lines[i]
+		switch {
+		case isMarker(l, '|') && b.SepMarker == "" && b.BaseMarker == "":
+			b.BaseMarker = l
+			b.BaseKnown = true
+			section = &b.Base
+		case isMarker(l, '=') && b.SepMarker == "":
+			b.SepMarker = l
+			section = &b.Theirs
+		case isMarker(l, '>') && b.SepMarker != "":
+			b.TheirsMarker = l
+			return b, i
+		case isMarker(l, '<'):
+			return nil, 0 // nested or malformed
+		default:
+			*section = append(*section, l)
+		}
+	}
+	return nil, 0
+}
+
+// isMarker reports whether line is a 7-character conflict marker of ch,
+// optionally followed by a label.
+func isMarker(line string, ch byte) bool {
+	if len(line) < 7 || strings.Count(line[:7], string(ch)) != 7 {
+		return false
+	}
+	if len(line) == 7 {
+		return true
+	}
+	switch line[7] {
+	case ' ', '\n', '\r':
+		return true
+	}
+	return false
+}
+
+func markerLabel(marker string) string {
+	if len(marker) < 7 {
+		return ""
+	}
+	return strings.TrimSpace(marker[7:])
+}
+
+// Blocks returns the conflict blocks in file order.
+func (f *ConflictFile) Blocks() []*ConflictBlock {
+	var out []*ConflictBlock
+	for _, s := range f.Segments {
+		if s.Block != nil {
+			out = append(out, s.Block)
+		}
+	}
+	return out
+}
+
+// BorrowBase copies each block's base from other, typically the diff3
+// re-merge of the same path, when the block counts agree. It reports
+// whether bases were borrowed.
+func (f *ConflictFile) BorrowBase(other *ConflictFile) bool {
+	mine, theirs := f.Blocks(), other.Blocks()
+	if len(mine) == 0 || len(mine) != len(theirs) {
+		return false
+	}
+	for i, b := range mine {
+		if !b.BaseKnown && theirs[i].BaseKnown {
+			b.Base = theirs[i].Base
+			b.BaseKnown = true
+		}
+	}
+	return true
+}
+
+// Resolve returns the file content with the i-th block replaced by the
+// chosen side. Other blocks are written back exactly as parsed.
+func (f *ConflictFile) Resolve(i int, side ConflictSide) string {
+	var sb strings.Builder
+	n := 0
+	for _, s := range f.Segments {
+		if s.Block == nil {
+			writeLines(&sb, s.Lines)
+			continue
+		}
+		b := s.Block
+		if n == i {
+			if side == TakeOurs {
+				writeLines(&sb, b.Ours)
+			} else {
+				writeLines(&sb, b.Theirs)
+			}
+		} else {
+			sb.WriteString(b.OursMarker)
+			writeLines(&sb, b.Ours)
+			if b.BaseMarker != "" {
+				sb.WriteString(b.BaseMarker)
+				writeLines(&sb, b.Base)
+			}
+			sb.WriteString(b.SepMarker)
+			writeLines(&sb, b.Theirs)
+			sb.WriteString(b.TheirsMarker)
+		}
+		n++
+	}
+	return sb.String()
+}
+
+func writeLines(sb *strings.Builder, lines []string) {
+	for _, l := range lines {
+		sb.WriteString(l)
+	}
+}
@@ -0,0 +1,68 @@
+package diffview
+
+import "testing"
+
+const conflicted = `top
+<<<<<<< HEAD
+ours 1
+=======
+theirs 1
+>>>>>>> feature
+middle
+<<<<<<< HEAD
+ours 2
+||||||| base
+base 2
+=======
+theirs 2
+>>>>>>> feature
+bottom
+`
+
+func TestParseConflicts(t *testing.T) {
+	f := ParseConflicts(conflicted)
+	blocks := f.Blocks()
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(blocks))
+	}
+	b := blocks[0]
+	if b.BaseKnown || b.OursLabel() != "HEAD" || b.TheirsLabel() != "feature" || b.Ours[0] != "ours 1\n" || b.Theirs[0] != "theirs 1\n" {
+		t.Fatalf("unexpected first block: %+v", b)
+	}
+	if !blocks[1].BaseKnown || blocks[1].Base[0] != "base 2\n" {
+		t.Fatalf("expected diff3 base in second block: %+v", blocks[1])
+	}
+}
+
+func TestConflictResolve(t *testing.T) {
+	f := ParseConflicts(conflicted)
+	got := f.Resolve(1, TakeTheirs)
+	want := `top
+<<<<<<< HEAD
+ours 1
+=======
+theirs 1
+>>>>>>> feature
+middle
+theirs 2
+bottom
+`
+	if got != want {
+		t.Fatalf("unexpected resolution:\n%s", got)
+	}
+	if again := ParseConflicts(got).Resolve(0, TakeOurs); again != "top\nours 1\nmiddle\ntheirs 2\nbottom\n" {
+		t.Fatalf("unexpected second resolution:\n%s", again)
+	}
+}
+
+func TestConflictBorrowBase(t *testing.T) {
+	f := ParseConflicts("<<<<<<< a\nx\n=======\ny\n>>>>>>> b\n")
+	d3 := ParseConflicts("<<<<<<< ours\nx\n||||||| base\nw\n=======\ny\n>>>>>>> theirs\n")
+	if !f.BorrowBase(d3) || !f.Blocks()[0].BaseKnown || f.Blocks()[0].Base[0] != "w\n" {
+		t.Fatalf("expected base to be borrowed: %+v", f.Blocks()[0])
+	}
+	// Borrowing must not change what is written back.
+	if got := f.Resolve(-1, TakeOurs); got != "<<<<<<< a\nx\n=======\ny\n>>>>>>> b\n" {
+		t.Fatalf("borrowed base leaked into file:\n%s", got)
+	}
+}
@@ -0,0 +1,138 @@
+package diffview
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Span marks bytes [Start, End) of a line.
+type Span struct {
+	Start, End int
+}
+
+// maxIntraLineCells bounds the token LCS table for very long lines.
+const maxIntraLineCells = 250000
+
+// IntraLine compares the two sides of a replaced line token by token (words,
+// whitespace runs and single punctuation characters) and returns the spans
+// that differ on each side. Both are nil when the lines share nothing but
+// whitespace, so a rewritten line is not painted as one big change.
+func IntraLine(old, new string) (oldSpans, newSpans []Span) {
+	a, b := tokenize(old), tokenize(new)
+	n, m := len(a), len(b)
+	if n*m > maxIntraLineCells {
+		return nil, nil
+	}
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i].text == b[j].text {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	aChanged := make([]bool, n)
+	bChanged := make([]bool, m)
+	common := false
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i].text == b[j].text:
+			if !isSpaceToken(a[i].text) {
+				common = true
+			}
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			aChanged[i] = true
+			i++
+		default:
+			bChanged[j] = true
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		aChanged[i] = true
+	}
+	for ; j < m; j++ {
+		bChanged[j] = true
+	}
+	if !common {
+		return nil, nil
+	}
+	return spansOf(a, aChanged), spansOf(b, bChanged)
+}
+
+type token struct {
+	text  string
+	start int
+}
+
+// tokenize splits s into words (letters, digits, '_'), whitespace runs and
+// single other characters.
+func tokenize(s string) []token {
+	var toks []token
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		end := i + size
+		switch {
+		case isWordRune(r):
+			for end < len(s) {
+				r2, sz := utf8.DecodeRuneInString(s[end:])
+				if !isWordRune(r2) {
+					break
+				}
+				end += sz
+			}
+		case unicode.IsSpace(r):
+			for end < len(s) {
+				r2, sz := utf8.DecodeRuneInString(s[end:])
+				if !unicode.IsSpace(r2) {
+					break
+				}
+				end += sz
+			}
+		}
+		toks = append(toks, token{text: s[i:end], start: i})
+		i = end
+	}
+	return toks
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isSpaceToken(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsSpace(r)
+}
+
+// spansOf merges changed tokens into spans, bridging gaps that are only
+// whitespace so "a b" reads as one change rather than two.
+func spansOf(toks []token, changed []bool) []Span {
+	var spans []Span
+	for k, t := range toks {
+		if !changed[k] {
+			continue
+		}
+		end := t.start + len(t.text)
+		if n := len(spans); n > 0 && spans[n-1].End == t.start {
+			spans[n-1].End = end
+			continue
+		}
+		if n := len(spans); n > 0 && k > 0 && !changed[k-1] && isSpaceToken(toks[k-1].text) &&
+			spans[n-1].End == toks[k-1].start {
+			spans[n-1].End = end
+			continue
+		}
+		spans = append(spans, Span{Start: t.start, End: end})
+	}
+	return spans
+}
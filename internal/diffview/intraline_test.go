@@ -0,0 +1,34 @@
+package diffview
+
+import "testing"
+
+func TestIntraLine(t *testing.T) {
+	old, new := "return the quick brown fox", "return the quick red fox"
+	os, ns := IntraLine(old, new)
+	if len(os) != 1 || old[os[0].Start:os[0].End] != "brown" {
+		t.Fatalf("unexpected old spans: %+v", os)
+	}
+	if len(ns) != 1 || new[ns[0].Start:ns[0].End] != "red" {
+		t.Fatalf("unexpected new spans: %+v", ns)
+	}
+
+	// Changed words separated only by unchanged whitespace form one span.
+	old, new = "x := a b", "x := c d"
+	_, ns = IntraLine(old, new)
+	if len(ns) != 1 || new[ns[0].Start:ns[0].End] != "c d" {
+		t.Fatalf("expected one bridged span: %+v", ns)
+	}
+
+	// Nothing in common: no emphasis at all.
+	if os, ns := IntraLine("alpha", "beta gamma"); os != nil || ns != nil {
+		t.Fatalf("expected nil spans, got %+v %+v", os, ns)
+	}
+}
+
+func TestBuildRows_ReplaceSpans(t *testing.T) {
+	rows := BuildRowsFromUnified("@@ -1 +1 @@\n-f(a, b)\n+f(a, c)\n")
+	r := rows[1]
+	if r.Kind != RowReplace || len(r.RightSpans) != 1 || r.Right[r.RightSpans[0].Start:r.RightSpans[0].End] != "c" {
+		t.Fatalf("unexpected row: %+v", r)
+	}
+}
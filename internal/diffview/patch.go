@@ -0,0 +1,385 @@
+package diffview
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrEmptyPatch is returned when a patch would contain no changed lines.
+var ErrEmptyPatch = errors.New("no changes selected")
+
+// ParseHunkHeader extracts the ranges from a "@@ -a,b +c,d @@" header.
+// Omitted counts default to 1, as in unified diff format.
+func ParseHunkHeader(meta string) (oldStart, oldCount, newStart, newCount int, ok bool) {
+	if !strings.HasPrefix(meta, "@@ ") {
+		return 0, 0, 0, 0, false
+	}
+	fields := strings.Fields(meta)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, 0, 0, false
+	}
+	var err1, err2 error
+	oldStart, oldCount, err1 = parseRange(fields[1][1:])
+	newStart, newCount, err2 = parseRange(fields[2][1:])
+	if err1 != nil || err2 != nil {
+		return 0, 0, 0, 0, false
+	}
+	return oldStart, oldCount, newStart, newCount, true
+}
+
+func parseRange(s string) (start, count int, err error) {
+	count = 1
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		if count, err = strconv.Atoi(s[i+1:]); err != nil {
+			return 0, 0, err
+		}
+		s = s[:i]
+	}
+	start, err = strconv.Atoi(s)
+	return start, count, err
+}
+
+// IsChange reports whether the row carries an added or deleted line.
+func (r Row) IsChange() bool {
+	return r.Kind == RowAdd || r.Kind == RowDel || r.Kind == RowReplace
+}
+
+// HunkBounds returns the row range [start, end) of the hunk containing row i.
+// start is the index of the RowHunk header row.
+func HunkBounds(rows []Row, i int) (start, end int, ok bool) {
+	if i < 0 || i >= len(rows) {
+		return 0, 0, false
+	}
+	start = -1
+	for j := i; j >= 0; j-- {
+		if rows[j].Kind == RowHunk {
+			start = j
+			break
+		}
+		if rows[j].Kind == RowMeta {
+			break
+		}
+	}
+	if start < 0 {
+		return 0, 0, false
+	}
+	end = start + 1
+	for end < len(rows) && rows[end].Kind != RowHunk && rows[end].Kind != RowMeta {
+		end++
+	}
+	return start, end, true
+}
+
+// NewPosition returns the 1-based line and byte column in the new file that
+// row i refers to. A deleted line maps to the closest new-side line of its
+// hunk: the next one, else the previous one, else where the hunk starts.
+// The column points at the first change within a replaced line and is 1
+// otherwise. ok is false outside a hunk.
+func NewPosition(rows []Row, i int) (line, col int, ok bool) {
+	start, end, ok := HunkBounds(rows, i)
+	if !ok || i == start {
+		return 0, 0, false
+	}
+	col = 1
+	if r := rows[i]; r.Kind == RowReplace && len(r.RightSpans) > 0 {
+		col = r.RightSpans[0].Start + 1
+	}
+	for j := i; j < end; j++ {
+		if rows[j].RightLine > 0 {
+			return rows[j].RightLine, col, true
+		}
+	}
+	for j := i - 1; j > start; j-- {
+		if rows[j].RightLine > 0 {
+			return rows[j].RightLine, col, true
+		}
+	}
+	_, _, newStart, _, _ := ParseHunkHeader(rows[start].Meta)
+	return max(newStart, 1), col, true
+}
+
+// MapSelection maps the changes in rows[from:to] onto other, a diff of the
+// same new file against a different old side (e.g. the index instead of
+// HEAD). It returns the range of other covering the changes at the same
+// places in the new file, to pass to BuildPatch; ok is false when other has
+// none there, e.g. because they are already staged.
+func MapSelection(rows []Row, from, to int, other []Row) (ofrom, oto int, ok bool) {
+	lo, hi := -1, -1
+	for i := max(from, 0); i < min(to, len(rows)); i++ {
+		if !rows[i].IsChange() {
+			continue
+		}
+		a := newAnchor(rows, i)
+		if lo < 0 || a < lo {
+			lo = a
+		}
+		hi = max(hi, a)
+	}
+	if lo < 0 {
+		return 0, 0, false
+	}
+	ofrom = -1
+	for i := range other {
+		if !other[i].IsChange() {
+			continue
+		}
+		if a := newAnchor(other, i); a >= lo && a <= hi {
+			if ofrom < 0 {
+				ofrom = i
+			}
+			oto = i + 1
+		}
+	}
+	return ofrom, oto, ofrom >= 0
+}
+
+// newAnchor orders change row i by its place in the new file: twice its new
+// line number, or for a deleted line one less than twice the number of the
+// new line it precedes.
+func newAnchor(rows []Row, i int) int {
+	if rows[i].RightLine > 0 {
+		return 2 * rows[i].RightLine
+	}
+	start, end, _ := HunkBounds(rows, i)
+	for j := i + 1; j < end; j++ {
+		if rows[j].RightLine > 0 {
+			return 2*rows[j].RightLine - 1
+		}
+	}
+	for j := i - 1; j > start; j-- {
+		if rows[j].RightLine > 0 {
+			return 2*rows[j].RightLine + 1
+		}
+	}
+	// only deletions: they follow line newStart
+	_, _, newStart, _, _ := ParseHunkHeader(rows[start].Meta)
+	return 2*newStart + 1
+}
+
+// fileHeader returns the metadata rows (diff --git, index, ---/+++ ...)
+// describing the file that the hunk starting at row start belongs to.
+func fileHeader(rows []Row, start int) []string {
+	j := start - 1
+	for j >= 0 && rows[j].Kind != RowMeta {
+		j--
+	}
+	end := j + 1
+	for j >= 0 && rows[j].Kind == RowMeta {
+		j--
+	}
+	header := make([]string, 0, end-j-1)
+	for _, r := range rows[j+1 : end] {
+		header = append(header, r.Meta)
+	}
+	return header
+}
+
+// BuildPatch builds a unified patch that carries only the changed rows in
+// rows[from:to]; changes outside the range are neutralized the way
+// `git add -p` does when editing hunks. Set reverse when the patch is going
+// to be applied in reverse (unstaging or discarding): unselected additions
+// then become context and unselected deletions are dropped, instead of the
+// other way around.
+func BuildPatch(rows []Row, from, to int, reverse bool) (string, error) {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(rows) {
+		to = len(rows)
+	}
+	var b strings.Builder
+	var lastHeader string
+	for i := 0; i < len(rows); {
+		if rows[i].Kind != RowHunk {
+			i++
+			continue
+		}
+		start, end, _ := HunkBounds(rows, i)
+		i = end
+		if end <= from || start >= to {
+			continue
+		}
+		body, oldN, newN, changed, partial := hunkBody(rows[start+1:end], from-start-1, to-start-1, reverse)
+		if !changed {
+			continue
+		}
+		oldStart, _, newStart, _, ok := ParseHunkHeader(rows[start].Meta)
+		if !ok {
+			return "", fmt.Errorf("malformed hunk header: %q", rows[start].Meta)
+		}
+		if oldN > 0 && oldStart == 0 {
+			oldStart = 1
+		}
+		if newN > 0 && newStart == 0 {
+			newStart = 1
+		}
+		header := adjustHeader(fileHeader(rows, start), partial, reverse)
+		if h := strings.Join(header, "\n"); h != lastHeader || b.Len() == 0 {
+			for _, l := range header {
+				b.WriteString(l)
+				b.WriteByte('\n')
+			}
+			lastHeader = h
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", formatRange(oldStart, oldN), formatRange(newStart, newN))
+		b.WriteString(body)
+	}
+	if b.Len() == 0 {
+		return "", ErrEmptyPatch
+	}
+	return b.String(), nil
+}
+
+// hunkBody renders the lines of one hunk. Rows with index in [from, to) keep
+// their changes. It reports the resulting line counts, whether any change
+// survived, and whether some change was neutralized.
+func hunkBody(rows []Row, from, to int, reverse bool) (body string, oldN, newN int, changed, partial bool) {
+	var b strings.Builder
+	line := func(prefix byte, text string, noEOL bool) {
+		b.WriteByte(prefix)
+		b.WriteString(text)
+		b.WriteByte('\n')
+		if noEOL {
+			b.WriteString("\\ No newline at end of file\n")
+		}
+	}
+	// Deletions of a replace row are emitted before its addition; keeping
+	// both sides in order is all git apply needs.
+	for i, r := range rows {
+		sel := i >= from && i < to
+		hasDel := r.Kind == RowDel || r.Kind == RowReplace
+		hasAdd := r.Kind == RowAdd || r.Kind == RowReplace
+		if r.Kind == RowContext {
+			line(' ', r.Left, r.LeftNoEOL && r.RightNoEOL)
+			oldN++
+			newN++
+			continue
+		}
+		if hasDel {
+			switch {
+			case sel:
+				line('-', r.Left, r.LeftNoEOL)
+				oldN++
+				changed = true
+			case !reverse:
+				line(' ', r.Left, r.LeftNoEOL)
+				oldN++
+				newN++
+				partial = true
+			default:
+				partial = true
+			}
+		}
+		if hasAdd {
+			switch {
+			case sel:
+				line('+', r.Right, r.RightNoEOL)
+				newN++
+				changed = true
+			case reverse:
+				line(' ', r.Right, r.RightNoEOL)
+				oldN++
+				newN++
+				partial = true
+			default:
+				partial = true
+			}
+		}
+	}
+	return b.String(), oldN, newN, changed, partial
+}
+
+// adjustHeader rewrites a file creation (when reversing) or deletion (when
+// applying forward) into a plain modification if only part of the content
+// is selected, since git refuses to delete a file that keeps some lines.
+func adjustHeader(header []string, partial, reverse bool) []string {
+	if !partial {
+		return header
+	}
+	var oldName, newName string
+	for _, l := range header {
+		if strings.HasPrefix(l, "--- ") {
+			oldName = strings.TrimPrefix(l, "--- ")
+		} else if strings.HasPrefix(l, "+++ ") {
+			newName = strings.TrimPrefix(l, "+++ ")
+		}
+	}
+	out := make([]string, 0, len(header))
+	for _, l := range header {
+		switch {
+		case reverse && strings.HasPrefix(l, "new file mode "):
+			continue
+		case !reverse && strings.HasPrefix(l, "deleted file mode "):
+			continue
+		case reverse && l == "--- /dev/null" && strings.HasPrefix(newName, "b/"):
+			l = "--- a/" + strings.TrimPrefix(newName, "b/")
+		case !reverse && l == "+++ /dev/null" && strings.HasPrefix(oldName, "a/"):
+			l = "+++ b/" + strings.TrimPrefix(oldName, "a/")
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+func formatRange(start, count int) string {
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// WithoutRename rewrites the file header of a rename or copy patch so it
+// applies in place to the new path. Reverting content (unstaging, discarding)
+// must not move the file back to its old name.
+func WithoutRename(patch string) string {
+	lines := strings.SplitAfter(patch, "\n")
+	newName := ""
+	for _, l := range lines {
+		if strings.HasPrefix(l, "@@") {
+			break
+		}
+		if strings.HasPrefix(l, "+++ ") {
+			newName = strings.TrimSuffix(strings.TrimPrefix(l, "+++ "), "\n")
+		}
+	}
+	if newName == "" || newName == "/dev/null" {
+		return patch
+	}
+	oldName := swapPrefix(newName, "b/", "a/")
+	var b strings.Builder
+	inHeader := true
+	for _, l := range lines {
+		if strings.HasPrefix(l, "@@") {
+			inHeader = false
+		} else if strings.HasPrefix(l, "diff --git ") {
+			inHeader = true
+		}
+		if inHeader {
+			switch {
+			case strings.HasPrefix(l, "diff --git "):
+				l = "diff --git " + oldName + " " + newName + "\n"
+			case strings.HasPrefix(l, "--- "):
+				l = "--- " + oldName + "\n"
+			case strings.HasPrefix(l, "similarity index "), strings.HasPrefix(l, "dissimilarity index "),
+				strings.HasPrefix(l, "rename from "), strings.HasPrefix(l, "rename to "),
+				strings.HasPrefix(l, "copy from "), strings.HasPrefix(l, "copy to "):
+				continue
+			}
+		}
+		b.WriteString(l)
+	}
+	return b.String()
+}
+
+// swapPrefix replaces the a/ or b/ prefix of a (possibly quoted) patch path.
+func swapPrefix(name, from, to string) string {
+	if strings.HasPrefix(name, `"`+from) {
+		return `"` + to + name[len(from)+1:]
+	}
+	if strings.HasPrefix(name, from) {
+		return to + name[len(from):]
+	}
+	return name
+}
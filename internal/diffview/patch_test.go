@@ -0,0 +1,246 @@
+package diffview
+
+import (
+	"strings"
+	"testing"
+)
+
+const twoHunks = `diff --git a/a.txt b/a.txt
+index 1111111..2222222 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1,3 +1,3 @@
+ one
+-two
++TWO
+ three
+@@ -8,3 +8,4 @@
+ eight
+ nine
++nine and a half
+ ten`
+
+func TestBuildPatch_SingleHunk(t *testing.T) {
+	rows := BuildRowsFromUnified(twoHunks)
+	// find second hunk
+	idx := -1
+	for i, r := range rows {
+		if r.Kind == RowAdd {
+			idx = i
+		}
+	}
+	start, end, ok := HunkBounds(rows, idx)
+	if !ok {
+		t.Fatalf("expected hunk bounds for row %d", idx)
+	}
+	patch, err := BuildPatch(rows, start, end, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `diff --git a/a.txt b/a.txt
+index 1111111..2222222 100644
+--- a/a.txt
++++ b/a.txt
+@@ -8,3 +8,4 @@
+ eight
+ nine
++nine and a half
+ ten
+`
+	if patch != want {
+		t.Fatalf("unexpected patch:\n%s", patch)
+	}
+}
+
+func TestBuildPatch_PartialLines(t *testing.T) {
+	unified := `--- a/f
++++ b/f
+@@ -1,2 +1,3 @@
+-x = old
++x = new1
++x = new2
+ keep`
+	rows := BuildRowsFromUnified(unified)
+	// rows: meta, meta, hunk, replace(x = old, x = new1), add(x = new2), context
+	if rows[4].Kind != RowAdd {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+	patch, err := BuildPatch(rows, 4, 5, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(patch, "@@ -1,2 +1,3 @@\n x = old\n+x = new2\n keep\n") {
+		t.Fatalf("forward partial patch wrong:\n%s", patch)
+	}
+	patch, err = BuildPatch(rows, 4, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(patch, "@@ -1,2 +1,3 @@\n x = new1\n+x = new2\n keep\n") {
+		t.Fatalf("reverse partial patch wrong:\n%s", patch)
+	}
+}
+
+func TestBuildPatch_NoNewlineAndDeletion(t *testing.T) {
+	unified := `diff --git a/gone b/gone
+deleted file mode 100644
+index 3333333..0000000
+--- a/gone
++++ /dev/null
+@@ -1,2 +0,0 @@
+-a
+-b
+\ No newline at end of file`
+	rows := BuildRowsFromUnified(unified)
+	full, err := BuildPatch(rows, 0, len(rows), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full != unified+"\n" {
+		t.Fatalf("full patch should round-trip:\n%s", full)
+	}
+	// Only the first line: the file can no longer be deleted.
+	var first int
+	for i, r := range rows {
+		if r.Kind == RowDel {
+			first = i
+			break
+		}
+	}
+	partial, err := BuildPatch(rows, first, first+1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(partial, "deleted file mode") || !strings.Contains(partial, "+++ b/gone") {
+		t.Fatalf("partial deletion should become a modification:\n%s", partial)
+	}
+	if !strings.Contains(partial, "@@ -1,2 +1 @@\n-a\n b\n\\ No newline at end of file\n") {
+		t.Fatalf("unexpected partial hunk:\n%s", partial)
+	}
+}
+
+func TestBuildPatch_Empty(t *testing.T) {
+	rows := BuildRowsFromUnified(twoHunks)
+	if _, err := BuildPatch(rows, 0, 1, false); err != ErrEmptyPatch {
+		t.Fatalf("expected ErrEmptyPatch, got %v", err)
+	}
+}
+
+func TestWithoutRename(t *testing.T) {
+	patch := `diff --git a/old.go b/new.go
+similarity index 95%
+rename from old.go
+rename to new.go
+index 1111111..2222222 100644
+--- a/old.go
++++ b/new.go
+@@ -1 +1,2 @@
+ x
++y
+`
+	want := `diff --git a/new.go b/new.go
+index 1111111..2222222 100644
+--- a/new.go
++++ b/new.go
+@@ -1 +1,2 @@
+ x
++y
+`
+	if got := WithoutRename(patch); got != want {
+		t.Fatalf("unexpected patch:\n%s", got)
+	}
+}
+
+func TestNewPosition(t *testing.T) {
+	rows := BuildRowsFromUnified(`--- a/f
++++ b/f
+@@ -1,4 +1,3 @@
+ a
+-x = old
++x = new
+-gone
+ d
+@@ -10,2 +9,0 @@
+-y
+-z`)
+	want := map[string][3]int{ // row text: line, col, ok
+		"a":       {1, 1, 1},
+		"x = new": {2, 5, 1},
+		"gone":    {3, 1, 1},
+		"d":       {3, 1, 1},
+		"y":       {9, 1, 1},
+		"z":       {9, 1, 1},
+	}
+	for i, r := range rows {
+		line, col, ok := NewPosition(rows, i)
+		if r.Kind == RowHunk || r.Kind == RowMeta {
+			if ok {
+				t.Fatalf("row %d (%q) has a position", i, r.Meta)
+			}
+			continue
+		}
+		text := r.Right
+		if r.Kind == RowDel {
+			text = r.Left
+		}
+		w, found := want[text]
+		if !found {
+			t.Fatalf("unexpected row %q", text)
+		}
+		if line != w[0] || col != w[1] || !ok {
+			t.Fatalf("%q: got %d:%d ok=%v, want %d:%d", text, line, col, ok, w[0], w[1])
+		}
+	}
+}
+
+func TestMapSelection(t *testing.T) {
+	// HEAD against the working tree; "b -> B" is already staged
+	head := BuildRowsFromUnified(`--- a/f
++++ b/f
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+@@ -14,3 +14,4 @@
+ n
+-o
++O
++new
+ p`)
+	// the index against the working tree
+	index := BuildRowsFromUnified(`--- a/f
++++ b/f
+@@ -14,3 +14,4 @@
+ n
+-o
++O
++new
+ p`)
+	first, _, _ := HunkBounds(head, 2)
+	second := 0
+	for i, r := range head {
+		if r.Kind == RowHunk && i > first {
+			second = i
+		}
+	}
+	if _, _, ok := MapSelection(head, first, second, index); ok {
+		t.Fatal("expected the staged hunk to map to nothing")
+	}
+	from, to, ok := MapSelection(head, second, len(head), index)
+	if !ok {
+		t.Fatal("expected the unstaged hunk to map")
+	}
+	patch, err := BuildPatch(index, from, to, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(patch, "@@ -14,3 +14,4 @@\n n\n-o\n+O\n+new\n p\n") {
+		t.Fatalf("unexpected patch:\n%s", patch)
+	}
+	// a single added line maps to just that line
+	from, to, _ = MapSelection(head, len(head)-2, len(head)-1, index)
+	if to-from != 1 || index[from].Right != "new" {
+		t.Fatalf("expected only the added line, got rows [%d,%d)", from, to)
+	}
+}
@@ -23,39 +23,70 @@ type Row struct {
 	Right string
 	Kind  RowKind
 	Meta  string // for hunk header text
+
+	// LeftLine/RightLine are 1-based line numbers in the old and new file;
+	// zero when the row has no line on that side.
+	LeftLine  int
+	RightLine int
+
+	// LeftNoEOL/RightNoEOL record a "\ No newline at end of file" marker
+	// following the line on that side, so patches can be rebuilt exactly.
+	LeftNoEOL  bool
+	RightNoEOL bool
+
+	// LeftSpans/RightSpans mark the changed parts of a RowReplace (see
+	// IntraLine); nil means the whole line changed.
+	LeftSpans  []Span
+	RightSpans []Span
 }
 
 // BuildRowsFromUnified parses a unified diff string into side-by-side rows.
-// It uses a simple pairing strategy within each hunk: deletions are paired
-// with subsequent additions as replacements; any remaining lines are shown
+// Within each block of changed lines, similar deletions and additions are
+// paired as replacements (see alignChanges); the remaining lines are shown
 // as left-only (deletions) or right-only (additions).
 func BuildRowsFromUnified(unified string) []Row {
 	s := bufio.NewScanner(strings.NewReader(unified))
 	s.Buffer(make([]byte, 0, 64*1024), 10*1024*1024) // allow large lines
 
 	rows := make([]Row, 0, 256)
-	pendingDel := make([]string, 0)
+	pendingDel := make([]Row, 0)
+	pendingAdd := make([]Row, 0)
 
 	flushPending := func() {
-		for _, dl := range pendingDel {
-			rows = append(rows, Row{Left: trimPrefix(dl), Right: "", Kind: RowDel})
-		}
+		rows = append(rows, alignChanges(pendingDel, pendingAdd)...)
 		pendingDel = pendingDel[:0]
+		pendingAdd = pendingAdd[:0]
 	}
 
 	inHunk := false
+	inHeader := false
+	var last byte        // prefix of the previous hunk line, for "\ No newline" markers
+	var oldLn, newLn int // next line numbers, from the hunk header
 	for s.Scan() {
 		line := s.Text()
-		if strings.HasPrefix(line, "diff --git ") || strings.HasPrefix(line, "index ") || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ") {
-			// Metadata; flush any pending deletions
+		if strings.HasPrefix(line, "diff --git ") {
 			flushPending()
 			rows = append(rows, Row{Kind: RowMeta, Meta: line})
+			inHunk = false
+			inHeader = true
 			continue
 		}
+		if inHeader || (!inHunk && isHeaderLine(line)) {
+			// Extended header lines (index, modes, renames, ---/+++) are
+			// kept as metadata so a patch can be rebuilt from the rows.
+			if !strings.HasPrefix(line, "@@ ") {
+				flushPending()
+				rows = append(rows, Row{Kind: RowMeta, Meta: line})
+				continue
+			}
+		}
 		if strings.HasPrefix(line, "@@ ") {
 			flushPending()
 			rows = append(rows, Row{Kind: RowHunk, Meta: line})
+			oldLn, _, newLn, _, _ = ParseHunkHeader(line)
 			inHunk = true
+			inHeader = false
+			last = 0
 			continue
 		}
 		if !inHunk {
@@ -66,7 +97,10 @@ func BuildRowsFromUnified(unified string) []Row {
 		if len(line) == 0 {
 			// blank line inside hunk: treat as context
 			flushPending()
-			rows = append(rows, Row{Left: "", Right: "", Kind: RowContext})
+			rows = append(rows, Row{Left: "", Right: "", Kind: RowContext, LeftLine: oldLn, RightLine: newLn})
+			oldLn++
+			newLn++
+			last = ' '
 			continue
 		}
 
@@ -74,26 +108,51 @@ func BuildRowsFromUnified(unified string) []Row {
 		case ' ':
 			flushPending()
 			t := trimPrefix(line)
-			rows = append(rows, Row{Left: t, Right: t, Kind: RowContext})
+			rows = append(rows, Row{Left: t, Right: t, Kind: RowContext, LeftLine: oldLn, RightLine: newLn})
+			oldLn++
+			newLn++
 		case '-':
-			pendingDel = append(pendingDel, line)
+			pendingDel = append(pendingDel, Row{Left: trimPrefix(line), Kind: RowDel, LeftLine: oldLn})
+			oldLn++
 		case '+':
-			if len(pendingDel) > 0 {
-				// Pair with the earliest pending deletion
-				dl := pendingDel[0]
-				pendingDel = pendingDel[1:]
-				rows = append(rows, Row{Left: trimPrefix(dl), Right: trimPrefix(line), Kind: RowReplace})
-			} else {
-				rows = append(rows, Row{Left: "", Right: trimPrefix(line), Kind: RowAdd})
+			pendingAdd = append(pendingAdd, Row{Right: trimPrefix(line), Kind: RowAdd, RightLine: newLn})
+			newLn++
+		case '\\':
+			// "\ No newline at end of file" applies to the previous line
+			switch last {
+			case '-':
+				if n := len(pendingDel); n > 0 {
+					pendingDel[n-1].LeftNoEOL = true
+				}
+			case '+':
+				if n := len(pendingAdd); n > 0 {
+					pendingAdd[n-1].RightNoEOL = true
+				}
+			case ' ':
+				if n := len(rows); n > 0 {
+					rows[n-1].LeftNoEOL = true
+					rows[n-1].RightNoEOL = true
+				}
 			}
+			continue
 		default:
 			// Unknown line; ignore
 		}
+		last = line[0]
 	}
 	flushPending()
+	for i := range rows {
+		if rows[i].Kind == RowReplace {
+			rows[i].LeftSpans, rows[i].RightSpans = IntraLine(rows[i].Left, rows[i].Right)
+		}
+	}
 	return rows
 }
 
+func isHeaderLine(line string) bool {
+	return strings.HasPrefix(line, "index ") || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ")
+}
+
 func trimPrefix(s string) string {
 	if s == "" {
 		return s
@@ -1,6 +1,10 @@
 package diffview
 
-import "testing"
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
 
 func TestBuildRows_SimpleReplaceAndAdd(t *testing.T) {
 	unified := `diff --git a/a.txt b/a.txt
@@ -58,3 +62,73 @@ func TestBuildRows_DeletionOnly(t *testing.T) {
 		t.Fatalf("expected 2 deletions, got %d", dels)
 	}
 }
+
+func TestBuildRows_LineNumbers(t *testing.T) {
+	unified := "@@ -10,3 +20,4 @@\n ctx\n-x = old\n+x = new\n+extra\n ctx2\n"
+	rows := BuildRowsFromUnified(unified)
+	want := []struct{ left, right int }{
+		{0, 0},   // hunk
+		{10, 20}, // ctx
+		{11, 21}, // old -> new
+		{0, 22},  // extra
+		{12, 23}, // ctx2
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d rows, got %d", len(want), len(rows))
+	}
+	for i, w := range want {
+		if rows[i].LeftLine != w.left || rows[i].RightLine != w.right {
+			t.Fatalf("row %d: got %d/%d, want %d/%d", i, rows[i].LeftLine, rows[i].RightLine, w.left, w.right)
+		}
+	}
+}
+
+func TestBuildRows_AlignsSimilarLines(t *testing.T) {
+	// The first line was deleted and the other two edited: pairing in order
+	// would match unrelated lines.
+	unified := `@@ -1,3 +1,2 @@
+-import "os"
+-total := count(items)
+-return total, nil
++total := count(items) + 1
++return total, err`
+	rows := BuildRowsFromUnified(unified)
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %+v", rows)
+	}
+	if rows[1].Kind != RowDel || rows[1].Left != `import "os"` {
+		t.Fatalf("expected pure deletion first, got %+v", rows[1])
+	}
+	for _, r := range rows[2:] {
+		if r.Kind != RowReplace {
+			t.Fatalf("expected replace row, got %+v", r)
+		}
+	}
+	if rows[2].LeftLine != 2 || rows[2].RightLine != 1 || rows[3].LeftLine != 3 || rows[3].RightLine != 2 {
+		t.Fatalf("unexpected line numbers: %+v", rows[2:])
+	}
+
+	// Unrelated lines are not paired at all.
+	rows = BuildRowsFromUnified("@@ -1 +1 @@\n-alpha\n+beta\n")
+	if rows[1].Kind != RowDel || rows[2].Kind != RowAdd {
+		t.Fatalf("expected delete then add, got %+v", rows)
+	}
+}
+
+func TestBuildRows_AlignBudget(t *testing.T) {
+	// Long similar lines would take too many word comparisons to align:
+	// the block is paired in order instead.
+	long := strings.Repeat("word ", 100)
+	var b strings.Builder
+	b.WriteString("@@ -1,151 +1,150 @@\n-x\n")
+	for i := 0; i < 150; i++ {
+		fmt.Fprintf(&b, "-%s%d\n", long, i)
+	}
+	for i := 0; i < 150; i++ {
+		fmt.Fprintf(&b, "+%s%d changed\n", long, i)
+	}
+	rows := BuildRowsFromUnified(b.String())
+	if len(rows) != 152 || rows[1].Kind != RowReplace || rows[1].Left != "x" || rows[151].Kind != RowDel {
+		t.Fatalf("expected the block paired in order, got %d rows starting %+v", len(rows), rows[1])
+	}
+}
@@ -0,0 +1,223 @@
+// Package export renders a review into a self-contained HTML page.
+package export
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/interpretive-systems/diffium/internal/diffview"
+)
+
+// Colors are the theme colors used on the page, given like theme.json does:
+// an ANSI color index ("34") or hex ("#22c55e").
+type Colors struct {
+	Add, Del         string
+	AddEmph, DelEmph string
+	Divider, Meta    string
+}
+
+// File is one file of the review.
+type File struct {
+	Path   string // repository path, for anchors
+	Name   string // as shown, e.g. "old → new" for renames
+	Status string // status letters, as in the file pane
+	Note   string // optional line above the diff (rename, binary, error)
+	Rows   []diffview.Row
+}
+
+// Page is a whole review.
+type Page struct {
+	Title     string
+	Subtitle  string // what was compared
+	Generated time.Time
+	Colors    Colors
+	Files     []File
+}
+
+// HTML writes p as a single HTML document with inline styles and no
+// external resources, so it can be opened offline or attached to a run.
+func HTML(w io.Writer, p Page) error {
+	files := make([]fileView, len(p.Files))
+	for i, f := range p.Files {
+		files[i] = fileView{File: f, ID: fmt.Sprintf("file-%d", i+1), Rows: rowViews(f.Rows)}
+	}
+	return page.Execute(w, struct {
+		Page
+		Files []fileView
+		Style template.CSS
+	}{p, files, style(p.Colors)})
+}
+
+type fileView struct {
+	File
+	ID   string
+	Rows []rowView
+}
+
+type rowView struct {
+	Class          string // "hunk", "ctx", "add", "del" or "rep"
+	Header         string
+	OldNum, NewNum string
+	Old, New       template.HTML
+	OldSide        string // "del" when the left cell is a deletion
+	NewSide        string // "add" when the right cell is an addition
+}
+
+func rowViews(rows []diffview.Row) []rowView {
+	out := make([]rowView, 0, len(rows))
+	for _, r := range rows {
+		v := rowView{OldNum: lineNum(r.LeftLine), NewNum: lineNum(r.RightLine)}
+		switch r.Kind {
+		case diffview.RowMeta:
+			continue
+		case diffview.RowHunk:
+			v.Class, v.Header = "hunk", r.Meta
+		case diffview.RowContext:
+			v.Class = "ctx"
+			v.Old, v.New = code(r.Left, nil), code(r.Right, nil)
+		case diffview.RowAdd:
+			v.Class, v.NewSide = "add", "add"
+			v.New = code(r.Right, nil)
+		case diffview.RowDel:
+			v.Class, v.OldSide = "del", "del"
+			v.Old = code(r.Left, nil)
+		case diffview.RowReplace:
+			v.Class, v.OldSide, v.NewSide = "rep", "del", "add"
+			v.Old, v.New = code(r.Left, r.LeftSpans), code(r.Right, r.RightSpans)
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func lineNum(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// code escapes s, wrapping the changed spans in <mark>.
+func code(s string, spans []diffview.Span) template.HTML {
+	var b strings.Builder
+	pos := 0
+	for _, sp := range spans {
+		if sp.Start < pos || sp.End > len(s) {
+			break
+		}
+		b.WriteString(html.EscapeString(s[pos:sp.Start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(s[sp.Start:sp.End]))
+		b.WriteString("</mark>")
+		pos = sp.End
+	}
+	b.WriteString(html.EscapeString(s[pos:]))
+	return template.HTML(b.String())
+}
+
+func style(c Colors) template.CSS {
+	return template.CSS(fmt.Sprintf(":root{--add:%s;--del:%s;--add-emph:%s;--del-emph:%s;--divider:%s;--meta:%s}",
+		cssColor(c.Add, "#00af00"), cssColor(c.Del, "#ff0000"),
+		cssColor(c.AddEmph, "#005f00"), cssColor(c.DelEmph, "#5f0000"),
+		cssColor(c.Divider, "#585858"), cssColor(c.Meta, "#5f5fff")))
+}
+
+// cssColor converts a theme color to CSS, using def when it is unset or not
+// understood.
+func cssColor(c, def string) string {
+	if strings.HasPrefix(c, "#") && (len(c) == 4 || len(c) == 7) {
+		if _, err := strconv.ParseUint(c[1:], 16, 32); err == nil {
+			return c
+		}
+		return def
+	}
+	n, err := strconv.Atoi(c)
+	if err != nil || n < 0 || n > 255 {
+		return def
+	}
+	return ansiHex(n)
+}
+
+// ansi16 are the xterm defaults for the 16 basic colors.
+var ansi16 = [16]string{
+	"#000000", "#800000", "#008000", "#808000", "#000080", "#800080", "#008080", "#c0c0c0",
+	"#808080", "#ff0000", "#00ff00", "#ffff00", "#0000ff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// ansiHex maps an xterm 256-color index to hex.
+func ansiHex(n int) string {
+	switch {
+	case n < 16:
+		return ansi16[n]
+	case n < 232:
+		n -= 16
+		level := func(v int) int {
+			if v == 0 {
+				return 0
+			}
+			return 55 + 40*v
+		}
+		return fmt.Sprintf("#%02x%02x%02x", level(n/36), level(n/6%6), level(n%6))
+	default:
+		g := 8 + 10*(n-232)
+		return fmt.Sprintf("#%02x%02x%02x", g, g, g)
+	}
+}
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+{{.Style}}
+body{margin:0;display:flex;background:#1c1c1c;color:#d0d0d0;font:13px/1.45 ui-monospace,SFMono-Regular,Menlo,Consolas,monospace}
+nav{position:sticky;top:0;height:100vh;overflow:auto;flex:0 0 280px;border-right:1px solid var(--divider);padding:12px 0}
+nav h1{font-size:14px;margin:0 12px 2px}
+nav p{margin:0 12px 10px;opacity:.6}
+nav a{display:block;padding:2px 12px;color:inherit;text-decoration:none;white-space:nowrap;overflow:hidden;text-overflow:ellipsis}
+nav a:hover{background:#303030}
+nav .st{display:inline-block;min-width:3ch;color:var(--meta)}
+main{flex:1;min-width:0;padding:12px 16px}
+section{margin-bottom:28px}
+h2{font-size:14px;margin:0 0 6px;padding-bottom:4px;border-bottom:1px solid var(--divider)}
+h2 .st{color:var(--meta);margin-right:1ch}
+.note{color:var(--meta);margin:0 0 6px}
+table{width:100%;border-collapse:collapse;table-layout:fixed}
+td{vertical-align:top;padding:0 6px;white-space:pre-wrap;word-break:break-all;tab-size:4}
+td.ln{width:5ch;text-align:right;opacity:.5;user-select:none}
+td.code{width:50%}
+td.code+td.ln{border-left:1px solid var(--divider)}
+tr.hunk td{opacity:.6;padding:4px 6px;border-top:1px dotted var(--divider)}
+td.add{color:var(--add)}
+td.del{color:var(--del)}
+td.add mark{background:var(--add-emph);color:inherit}
+td.del mark{background:var(--del-emph);color:inherit}
+</style>
+</head>
+<body>
+<nav>
+<h1>{{.Title}}</h1>
+<p>{{.Subtitle}} · {{.Generated.Format "2006-01-02 15:04"}}</p>
+{{range .Files}}<a href="#{{.ID}}"><span class="st">{{.Status}}</span>{{.Name}}</a>
+{{else}}<p>No changes</p>
+{{end}}</nav>
+<main>
+{{range .Files}}<section id="{{.ID}}">
+<h2><span class="st">{{.Status}}</span>{{.Name}}</h2>
+{{with .Note}}<p class="note">{{.}}</p>
+{{end}}{{if .Rows}}<table>
+{{range .Rows}}{{if eq .Class "hunk"}}<tr class="hunk"><td colspan="4">{{.Header}}</td></tr>
+{{else}}<tr class="{{.Class}}"><td class="ln">{{.OldNum}}</td><td class="code{{with .OldSide}} {{.}}{{end}}">{{.Old}}</td><td class="ln">{{.NewNum}}</td><td class="code{{with .NewSide}} {{.}}{{end}}">{{.New}}</td></tr>
+{{end}}{{end}}</table>
+{{end}}</section>
+{{end}}</main>
+</body>
+</html>
+`))
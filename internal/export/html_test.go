@@ -0,0 +1,51 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interpretive-systems/diffium/internal/diffview"
+)
+
+func TestHTML(t *testing.T) {
+	rows := diffview.BuildRowsFromUnified("@@ -1,2 +1,2 @@\n keep\n-if a < b {\n+if a < c {\n")
+	var b strings.Builder
+	err := HTML(&b, Page{
+		Title:     "review",
+		Generated: time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC),
+		Colors:    Colors{Add: "34", Del: "#ef4444"},
+		Files:     []File{{Path: "a.go", Name: "a.go", Status: "M", Rows: rows}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`--add:#00af00;--del:#ef4444`,
+		`<a href="#file-1"><span class="st">M</span>a.go</a>`,
+		`<td class="code del">if a &lt; <mark>b</mark> {</td>`,
+		`<td class="code add">if a &lt; <mark>c</mark> {</td>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in page:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<script") || strings.Contains(out, "http") {
+		t.Fatalf("page must be self-contained")
+	}
+}
+
+func TestCSSColor(t *testing.T) {
+	for c, want := range map[string]string{
+		"196":     "#ff0000",
+		"22":      "#005f00",
+		"240":     "#585858",
+		"#14532d": "#14532d",
+		"bogus":   "def",
+	} {
+		if got := cssColor(c, "def"); got != want {
+			t.Errorf("cssColor(%q) = %q, want %q", c, got, want)
+		}
+	}
+}
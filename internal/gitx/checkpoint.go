@@ -0,0 +1,131 @@
+package gitx
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CheckpointRef points at the newest automatic checkpoint of the working
+// tree; older checkpoints are its first-parent ancestors. Checkpoints are
+// Snapshots, so unchanged content shares objects with earlier ones.
+const CheckpointRef = "refs/diffium/checkpoints"
+
+// checkpointLimit is how many checkpoints pruning keeps. The chain is
+// rewritten once it holds twice as many, so the cost is paid rarely.
+var checkpointLimit = 500
+
+// Checkpoint records the working tree under CheckpointRef unless it matches
+// the newest checkpoint. created reports whether a new one was made.
+func Checkpoint(repoRoot string) (s Snapshot, created bool, err error) {
+	tree, err := WorktreeTree(repoRoot)
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+	var parents []string
+	old := "" // update-ref: the ref must not exist yet
+	if id, err := resolveCommit(repoRoot, CheckpointRef); err == nil {
+		latest, err := readSnapshot(repoRoot, id)
+		if err != nil {
+			return Snapshot{}, false, err
+		}
+		if latest.Tree == tree {
+			return latest, false, nil
+		}
+		parents, old = []string{id}, id
+	}
+	id, err := commitTree(repoRoot, tree, "diffium checkpoint", parents...)
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+	// compare-and-swap, so that racing checkpoints cannot drop one another
+	if out, err := exec.Command("git", "-C", repoRoot, "update-ref", CheckpointRef, id, old).CombinedOutput(); err != nil {
+		return Snapshot{}, false, fmt.Errorf("git update-ref: %w: %s", err, out)
+	}
+	s, err = readSnapshot(repoRoot, id)
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+	return s, true, pruneCheckpoints(repoRoot, id)
+}
+
+// pruneCheckpoints rewrites the chain ending at head to its newest
+// checkpointLimit checkpoints once it is twice that long, so that the older
+// ones become unreachable and git can collect them. The rewritten commits
+// keep their trees and dates.
+func pruneCheckpoints(repoRoot, head string) error {
+	out, err := exec.Command("git", "-C", repoRoot, "rev-list", "--first-parent", "--count", head).Output()
+	if err != nil {
+		return fmt.Errorf("git rev-list --count: %w", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil || n <= 2*checkpointLimit {
+		return nil
+	}
+	keep, err := checkpointLog(repoRoot, head, checkpointLimit)
+	if err != nil {
+		return err
+	}
+	var parents []string
+	for i := len(keep) - 1; i >= 0; i-- {
+		date := strconv.FormatInt(keep[i].Time.Unix(), 10) + " +0000"
+		id, err := commitTreeEnv(repoRoot, keep[i].Tree, "diffium checkpoint",
+			[]string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date}, parents...)
+		if err != nil {
+			return err
+		}
+		parents = []string{id}
+	}
+	// compare-and-swap: a checkpoint taken meanwhile prunes on its own
+	if out, err := exec.Command("git", "-C", repoRoot, "update-ref", CheckpointRef, parents[0], head).CombinedOutput(); err != nil {
+		return fmt.Errorf("git update-ref: %w: %s", err, out)
+	}
+	return nil
+}
+
+// Checkpoints returns up to limit checkpoints, newest first. It returns
+// none when no checkpoint has been taken.
+func Checkpoints(repoRoot string, limit int) ([]Snapshot, error) {
+	if _, err := resolveCommit(repoRoot, CheckpointRef); err != nil {
+		return nil, nil
+	}
+	return checkpointLog(repoRoot, CheckpointRef, limit)
+}
+
+// checkpointLog returns up to limit checkpoints of the chain ending at rev,
+// newest first.
+func checkpointLog(repoRoot, rev string, limit int) ([]Snapshot, error) {
+	out, err := exec.Command("git", "-C", repoRoot, "log", "--first-parent", "-n", strconv.Itoa(limit), "--format="+snapshotFormat, rev).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log %s: %w", rev, err)
+	}
+	var list []Snapshot
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		s, err := parseSnapshot(line)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// RestoreFile replaces path in the working tree with its content at rev,
+// or deletes it when rev does not have it. The index is not touched.
+func RestoreFile(repoRoot, rev, path string) error {
+	if err := exec.Command("git", "-C", repoRoot, "cat-file", "-e", rev+":"+path).Run(); err != nil {
+		err := os.Remove(filepath.Join(repoRoot, path))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	cmd := exec.Command("git", "--literal-pathspecs", "-C", repoRoot, "restore", "--source="+rev, "--worktree", "--", path)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git restore: %w: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package gitx
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckpointsAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q", "-b", "main")
+	a := filepath.Join(dir, "a.txt")
+	write(t, a, "v1\n")
+
+	first, created, err := Checkpoint(dir)
+	if err != nil || !created {
+		t.Fatalf("first checkpoint: created=%v err=%v", created, err)
+	}
+	if again, created, err := Checkpoint(dir); err != nil || created || again.ID != first.ID {
+		t.Fatalf("expected no checkpoint for an unchanged tree, got %+v created=%v err=%v", again, created, err)
+	}
+	write(t, a, "v2 (bad turn)\n")
+	write(t, filepath.Join(dir, "junk.txt"), "junk\n")
+	if _, created, err := Checkpoint(dir); err != nil || !created {
+		t.Fatalf("second checkpoint: created=%v err=%v", created, err)
+	}
+
+	list, err := Checkpoints(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[1].ID != first.ID {
+		t.Fatalf("expected two checkpoints, newest first, got %+v", list)
+	}
+
+	if err := RestoreFile(dir, first.ID, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreFile(dir, first.ID, "junk.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(a); string(b) != "v1\n" {
+		t.Fatalf("expected a.txt restored, got %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "junk.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected junk.txt removed, got %v", err)
+	}
+}
+
+func TestCheckpoint_PrunesOldCheckpoints(t *testing.T) {
+	defer func(n int) { checkpointLimit = n }(checkpointLimit)
+	checkpointLimit = 2
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q", "-b", "main")
+	a := filepath.Join(dir, "a.txt")
+
+	var snaps []Snapshot
+	for i := 1; i <= 5; i++ {
+		write(t, a, strings.Repeat("v\n", i))
+		s, _, err := Checkpoint(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snaps = append(snaps, s)
+	}
+	list, err := Checkpoints(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Tree != snaps[4].Tree || list[1].Tree != snaps[3].Tree {
+		t.Fatalf("expected the chain pruned to two checkpoints, got %+v", list)
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-list", CheckpointRef).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, old := range snaps[:3] {
+		if strings.Contains(string(out), old.ID) {
+			t.Fatalf("expected %s unreachable, rev-list:\n%s", old.ID, out)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package gitx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// ConflictDiff3 re-merges an unmerged path from its index stages (:1 base,
+// :2 ours, :3 theirs) with diff3-style markers, so each conflict's base is
+// available even when the working tree file was written without it. A
+// missing stage (add/add, modify/delete) is treated as empty.
+func ConflictDiff3(repoRoot, path string) (string, error) {
+	dir, err := os.MkdirTemp("", "diffium-merge-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	stages := []struct{ name, stage string }{{"ours", "2"}, {"base", "1"}, {"theirs", "3"}}
+	files := make([]string, len(stages))
+	for i, s := range stages {
+		b, _ := exec.Command("git", "-C", repoRoot, "show", ":"+s.stage+":"+path).Output()
+		files[i] = filepath.Join(dir, s.name)
+		if err := os.WriteFile(files[i], b, 0o600); err != nil {
+			return "", err
+		}
+	}
+	args := []string{"merge-file", "-p", "--diff3", "-L", "ours", "-L", "base", "-L", "theirs"}
+	cmd := exec.Command("git", append(args, files...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		// merge-file exits with the number of conflicts; only negative
+		// (>127) statuses are failures.
+		var ee *exec.ExitError
+		if !errors.As(err, &ee) || ee.ExitCode() > 127 {
+			return "", fmt.Errorf("git merge-file: %w", err)
+		}
+	}
+	return string(out), nil
+}
@@ -0,0 +1,43 @@
+package gitx
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConflictDiff3(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q", "-b", "main")
+	mustRun(t, dir, "git", "config", "user.email", "test@example.com")
+	mustRun(t, dir, "git", "config", "user.name", "Test User")
+	write(t, filepath.Join(dir, "f.txt"), "a\nbase\nc\n")
+	mustRun(t, dir, "git", "add", ".")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "base")
+	mustRun(t, dir, "git", "checkout", "-q", "-b", "feature")
+	write(t, filepath.Join(dir, "f.txt"), "a\ntheirs\nc\n")
+	mustRun(t, dir, "git", "commit", "-q", "-am", "theirs")
+	mustRun(t, dir, "git", "checkout", "-q", "main")
+	write(t, filepath.Join(dir, "f.txt"), "a\nours\nc\n")
+	mustRun(t, dir, "git", "commit", "-q", "-am", "ours")
+	// The merge is expected to stop with a conflict.
+	_ = exec.Command("git", "-C", dir, "merge", "-q", "feature").Run()
+
+	files, err := ChangedFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || !files[0].Conflicted {
+		t.Fatalf("expected a conflicted file: %+v", files)
+	}
+	d3, err := ConflictDiff3(dir, "f.txt")
+	if err != nil {
+		t.Fatalf("ConflictDiff3 error: %v", err)
+	}
+	for _, want := range []string{"<<<<<<< ours\nours\n", "||||||| base\nbase\n", "=======\ntheirs\n>>>>>>> theirs\n"} {
+		if !strings.Contains(d3, want) {
+			t.Fatalf("missing %q in:\n%s", want, d3)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package gitx
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"os/exec"
@@ -12,12 +11,19 @@ import (
 
 // FileChange represents a changed file in the repo.
 type FileChange struct {
-	Path      string
-	Staged    bool
-	Unstaged  bool
-	Untracked bool
-	Binary    bool
-	Deleted   bool
+	Path       string
+	Staged     bool
+	Unstaged   bool
+	Untracked  bool
+	Binary     bool
+	Deleted    bool
+	Conflicted bool // unmerged (merge/rebase conflict)
+	Submodule  bool
+	OldPath    string // source path when Renamed or Copied
+	Renamed    bool
+	Copied     bool
+	Similarity int  // rename/copy similarity score (0-100)
+	Added      bool // new file: untracked, staged as new, or added in a range
 }
 
 // RepoRoot resolves the git repository root from a given path (or current dir).
@@ -37,86 +43,18 @@ func RepoRoot(path string) (string, error) {
 	return root, nil
 }
 
-// ChangedFiles lists files changed relative to HEAD, combining staged, unstaged, and untracked.
-func ChangedFiles(repoRoot string) ([]FileChange, error) {
-	// Unstaged vs index (include deletions)
-	unstaged, err := listNames(repoRoot, []string{"diff", "--name-only", "--diff-filter=ACDMRTUXB"})
+// GitPath resolves a path inside the repository's git directory, as
+// `git rev-parse --git-path` does (so worktrees share what they should).
+func GitPath(repoRoot, name string) (string, error) {
+	out, err := exec.Command("git", "-C", repoRoot, "rev-parse", "--git-path", name).Output()
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("rev-parse --git-path %s: %w", name, err)
 	}
-	// Staged vs HEAD
-	staged, err := listNames(repoRoot, []string{"diff", "--name-only", "--cached", "--diff-filter=ACDMRTUXB"})
-	if err != nil {
-		return nil, err
-	}
-	// Untracked files
-	untracked, err := listNames(repoRoot, []string{"ls-files", "--others", "--exclude-standard"})
-	if err != nil {
-		return nil, err
-	}
-	// Deletions detail
-	deletedUnstaged, _ := listNames(repoRoot, []string{"ls-files", "-d"}) // deleted in WT, not staged
-	deletedStaged, _ := listNames(repoRoot, []string{"diff", "--cached", "--name-only", "--diff-filter=D"})
-
-	m := map[string]*FileChange{}
-	mark := func(paths []string, fn func(fc *FileChange)) {
-		for _, p := range paths {
-			if p == "" { // skip any empties
-				continue
-			}
-			if !filepath.IsAbs(p) {
-				// Keep paths relative to repo root for display
-			}
-			fc := m[p]
-			if fc == nil {
-				fc = &FileChange{Path: p}
-				m[p] = fc
-			}
-			fn(fc)
-		}
-	}
-	mark(unstaged, func(fc *FileChange) { fc.Unstaged = true })
-	mark(staged, func(fc *FileChange) { fc.Staged = true })
-	mark(untracked, func(fc *FileChange) { fc.Untracked = true })
-	mark(deletedUnstaged, func(fc *FileChange) { fc.Deleted = true; fc.Unstaged = true })
-	mark(deletedStaged, func(fc *FileChange) { fc.Deleted = true; fc.Staged = true })
-
-	// Determine potential binaries by probing diff header quickly
-	paths := make([]string, 0, len(m))
-	for p := range m {
-		paths = append(paths, p)
-	}
-	sort.Strings(paths)
-	out := make([]FileChange, 0, len(paths))
-	for _, p := range paths {
-		fc := m[p]
-		// Lightweight binary check: if unified diff says Binary files differ
-		if isBinary(repoRoot, p) {
-			fc.Binary = true
-		}
-		out = append(out, *fc)
-	}
-	return out, nil
-}
-
-func listNames(repoRoot string, args []string) ([]string, error) {
-	a := append([]string{"-C", repoRoot}, args...)
-	cmd := exec.Command("git", a...)
-	b, err := cmd.Output()
-	if err != nil {
-		// On empty sets git exits 0 with empty output; any non-0 means real error
-		// Return empty result for safety only when output is empty
-		return nil, fmt.Errorf("git %v: %w", strings.Join(args, " "), err)
-	}
-	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
-	out := make([]string, 0, len(lines))
-	for _, l := range lines {
-		l = strings.TrimSpace(l)
-		if l != "" {
-			out = append(out, l)
-		}
+	p := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(repoRoot, p)
 	}
-	return out, nil
+	return p, nil
 }
 
 // DiffHEAD returns a unified diff between HEAD and the working tree for a single file.
@@ -156,26 +94,71 @@ func DiffStaged(repoRoot, path string) (string, error) {
 	return string(b), nil
 }
 
-func isBinary(repoRoot, path string) bool {
-	var args []string
-	if isTracked(repoRoot, path) {
-		args = []string{"-C", repoRoot, "diff", "--numstat", "HEAD", "--", path}
-	} else {
-		args = []string{"-C", repoRoot, "diff", "--numstat", "--no-index", "/dev/null", path}
+// DiffUnstaged returns a unified diff between the index and the working
+// tree for a single file: the changes that are not staged yet, which is
+// what a staging patch must apply to.
+func DiffUnstaged(repoRoot, path string) (string, error) {
+	cmd := exec.Command("git", "--literal-pathspecs", "-C", repoRoot, "diff", "--no-color", "--text", "--", path)
+	b, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git diff: %w: %s", err, string(b))
 	}
-	cmd := exec.Command("git", args...)
-	b, _ := cmd.Output()
-	line := strings.TrimSpace(string(b))
-	if line == "" {
-		return false
+	return string(b), nil
+}
+
+// DiffHEADRenamed returns the diff between HEAD and the working tree for a
+// file renamed or copied from oldPath, so only the content delta is shown
+// instead of a deletion plus a full-file addition.
+func DiffHEADRenamed(repoRoot, oldPath, newPath string) (string, error) {
+	args := []string{"--no-color", "--text", "-M", "-C", "--find-copies-harder", "HEAD", "--", oldPath, newPath}
+	if !isTracked(repoRoot, newPath) {
+		// git only pairs paths it knows about; register the new one as
+		// intent-to-add in a throwaway index.
+		b, err := diffWithIntentToAdd(repoRoot, []string{newPath}, args...)
+		return string(b), err
+	}
+	cmd := exec.Command("git", append([]string{"--literal-pathspecs", "-C", repoRoot, "diff"}, args...)...)
+	b, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git diff: %w: %s", err, string(b))
 	}
-	// numstat returns "-\t-\tpath" for binary files
-	parts := strings.Split(line, "\t")
-	if len(parts) >= 2 && (parts[0] == "-" || parts[1] == "-") {
-		return true
+	return string(b), nil
+}
+
+// DiffStagedRenamed returns the diff between HEAD and the index for a file
+// renamed or copied from oldPath.
+func DiffStagedRenamed(repoRoot, oldPath, newPath string) (string, error) {
+	cmd := exec.Command("git", "--literal-pathspecs", "-C", repoRoot, "diff", "--no-color", "--text", "--cached", "-M", "-C", "--find-copies-harder", "HEAD", "--", oldPath, newPath)
+	b, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git diff --cached: %w: %s", err, string(b))
+	}
+	return string(b), nil
+}
+
+// DiffFile returns the diff of f against HEAD, from the index when staged
+// and from the working tree otherwise, following renames and copies.
+func DiffFile(repoRoot string, f FileChange, staged bool) (string, error) {
+	switch {
+	case staged && f.OldPath != "":
+		return DiffStagedRenamed(repoRoot, f.OldPath, f.Path)
+	case staged:
+		return DiffStaged(repoRoot, f.Path)
+	case f.OldPath != "":
+		return DiffHEADRenamed(repoRoot, f.OldPath, f.Path)
+	default:
+		return DiffHEAD(repoRoot, f.Path)
+	}
+}
+
+// ShowFile returns the content of path at rev, or in the index when rev is
+// empty.
+func ShowFile(repoRoot, rev, path string) (string, error) {
+	b, err := exec.Command("git", "-C", repoRoot, "show", rev+":"+path).Output()
+	if err != nil {
+		return "", fmt.Errorf("git show %s:%s: %w", rev, path, err)
 	}
-	// Fallback: detect textual mention just in case
-	return bytes.Contains(b, []byte("-\t-\t"))
+	return string(b), nil
 }
 
 func isTracked(repoRoot, path string) bool {
@@ -200,6 +183,36 @@ func StageFiles(repoRoot string, paths []string) error {
 	return nil
 }
 
+// StagePatch applies a (possibly partial) unified patch to the index.
+func StagePatch(repoRoot, patch string) error {
+	return applyPatch(repoRoot, patch, "--cached")
+}
+
+// UnstagePatch reverse-applies a patch taken from the staged diff to the index.
+func UnstagePatch(repoRoot, patch string) error {
+	return applyPatch(repoRoot, patch, "--cached", "-R")
+}
+
+// DiscardPatch reverse-applies a patch to the working tree only, reverting
+// the lines it adds. The index is left untouched.
+func DiscardPatch(repoRoot, patch string) error {
+	return applyPatch(repoRoot, patch, "-R")
+}
+
+func applyPatch(repoRoot, patch string, flags ...string) error {
+	if strings.TrimSpace(patch) == "" {
+		return errors.New("empty patch")
+	}
+	args := append([]string{"-C", repoRoot, "apply", "--whitespace=nowarn"}, flags...)
+	args = append(args, "-")
+	cmd := exec.Command("git", args...)
+	cmd.Stdin = strings.NewReader(patch)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git apply %s: %w: %s", strings.Join(flags, " "), err, string(out))
+	}
+	return nil
+}
+
 // Commit performs a git commit with the given message.
 func Commit(repoRoot, message string) error {
 	if strings.TrimSpace(message) == "" {
@@ -88,3 +88,60 @@ func write(t *testing.T, path, content string) {
 		t.Fatal(err)
 	}
 }
+
+func TestStageAndUnstagePatch(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q")
+	mustRun(t, dir, "git", "config", "user.email", "test@example.com")
+	mustRun(t, dir, "git", "config", "user.name", "Test User")
+	write(t, filepath.Join(dir, "f.txt"), "a\nb\nc\nd\ne\nf\ng\nh\n")
+	mustRun(t, dir, "git", "add", ".")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "init")
+
+	write(t, filepath.Join(dir, "f.txt"), "A\nb\nc\nd\ne\nf\ng\nH\n")
+	patch := "--- a/f.txt\n+++ b/f.txt\n@@ -1,2 +1,2 @@\n-a\n+A\n b\n"
+	if err := StagePatch(dir, patch); err != nil {
+		t.Fatalf("StagePatch error: %v", err)
+	}
+	staged, err := DiffStaged(dir, "f.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(staged, "+A") || strings.Contains(staged, "+H") {
+		t.Fatalf("expected only first hunk staged, got:\n%s", staged)
+	}
+	if err := UnstagePatch(dir, patch); err != nil {
+		t.Fatalf("UnstagePatch error: %v", err)
+	}
+	files, err := ChangedFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Staged || !files[0].Unstaged {
+		t.Fatalf("expected f.txt unstaged only, got %+v", files)
+	}
+}
+
+func TestDiscardPatch_WorkingTreeOnly(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q")
+	mustRun(t, dir, "git", "config", "user.email", "test@example.com")
+	mustRun(t, dir, "git", "config", "user.name", "Test User")
+	write(t, filepath.Join(dir, "f.txt"), "a\nb\n")
+	mustRun(t, dir, "git", "add", ".")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "init")
+
+	write(t, filepath.Join(dir, "f.txt"), "a\nkeep\nb\ndrop\n")
+	// Reverse-applying this removes only "drop"; "keep" stays as context.
+	patch := "--- a/f.txt\n+++ b/f.txt\n@@ -1,3 +1,4 @@\n a\n keep\n b\n+drop\n"
+	if err := DiscardPatch(dir, patch); err != nil {
+		t.Fatalf("DiscardPatch error: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "f.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "a\nkeep\nb\n" {
+		t.Fatalf("unexpected working tree content: %q", b)
+	}
+}
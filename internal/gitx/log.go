@@ -0,0 +1,66 @@
+package gitx
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogEntry is one commit in the history.
+type LogEntry struct {
+	Hash    string
+	Short   string
+	Author  string
+	Date    time.Time
+	Subject string
+	Parents []string
+}
+
+// Log returns up to limit commits reachable from HEAD, newest first. A
+// repository without commits yields an empty list.
+func Log(repoRoot string, limit int) ([]LogEntry, error) {
+	if _, err := resolveCommit(repoRoot, "HEAD"); err != nil {
+		return nil, nil
+	}
+	args := []string{"-C", repoRoot, "log", "-z", "--format=%H%x1f%h%x1f%an%x1f%at%x1f%P%x1f%s"}
+	if limit > 0 {
+		args = append(args, "-n", strconv.Itoa(limit))
+	}
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+	var commits []LogEntry
+	for _, rec := range strings.Split(string(out), "\x00") {
+		rec = strings.TrimPrefix(rec, "\n")
+		if rec == "" {
+			continue
+		}
+		f := strings.SplitN(rec, "\x1f", 6)
+		if len(f) != 6 {
+			return nil, fmt.Errorf("malformed log record: %q", rec)
+		}
+		secs, _ := strconv.ParseInt(f[3], 10, 64)
+		commits = append(commits, LogEntry{
+			Hash:    f[0],
+			Short:   f[1],
+			Author:  f[2],
+			Date:    time.Unix(secs, 0),
+			Subject: f[5],
+			Parents: strings.Fields(f[4]),
+		})
+	}
+	return commits, nil
+}
+
+// Range compares the commit with its first parent, or with the empty tree
+// for a root commit.
+func (c LogEntry) Range() Range {
+	base := emptyTree
+	if len(c.Parents) > 0 {
+		base = c.Parents[0]
+	}
+	return Range{Base: base, Head: c.Hash, Label: c.Short}
+}
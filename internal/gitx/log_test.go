@@ -0,0 +1,43 @@
+package gitx
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLogAndCommitRange(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q")
+	mustRun(t, dir, "git", "config", "user.email", "test@example.com")
+	mustRun(t, dir, "git", "config", "user.name", "Test User")
+	if commits, err := Log(dir, 10); err != nil || len(commits) != 0 {
+		t.Fatalf("expected empty history, got %v %v", commits, err)
+	}
+	write(t, filepath.Join(dir, "a.txt"), "a\n")
+	mustRun(t, dir, "git", "add", ".")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "first")
+	write(t, filepath.Join(dir, "b.txt"), "b\n")
+	mustRun(t, dir, "git", "add", ".")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "second: with | odd chars")
+
+	commits, err := Log(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 || commits[0].Subject != "second: with | odd chars" || commits[0].Author != "Test User" {
+		t.Fatalf("unexpected log: %+v", commits)
+	}
+	if len(commits[1].Parents) != 0 || len(commits[0].Parents) != 1 || commits[0].Date.IsZero() {
+		t.Fatalf("unexpected parents/date: %+v", commits)
+	}
+	// Each commit's range lists only its own changes, including the root.
+	for i, want := range []string{"b.txt", "a.txt"} {
+		files, err := RangeFiles(dir, commits[i].Range())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 || files[0].Path != want || !files[0].Added {
+			t.Fatalf("commit %d: unexpected files %+v", i, files)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package gitx
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Range selects two trees to compare instead of HEAD, the index and the
+// working tree.
+type Range struct {
+	Base  string // resolved commit (or tree) id
+	Head  string // resolved commit (or tree) id, or "" for the working tree
+	Label string // as given by the user, for display
+}
+
+// ParseRange resolves a revision range: "A...B" compares B with its merge
+// base with A, "A..B" compares A with B, and a single revision "A" means
+// "A...HEAD" (what the current branch added since it forked from A).
+func ParseRange(repoRoot, spec string) (Range, error) {
+	spec = strings.TrimSpace(spec)
+	switch {
+	case strings.Contains(spec, "..."):
+		parts := strings.SplitN(spec, "...", 2)
+		a, b := orHEAD(parts[0]), orHEAD(parts[1])
+		out, err := exec.Command("git", "-C", repoRoot, "merge-base", a, b).Output()
+		if err != nil {
+			return Range{}, fmt.Errorf("git merge-base %s %s: %w", a, b, err)
+		}
+		head, err := resolveCommit(repoRoot, b)
+		if err != nil {
+			return Range{}, err
+		}
+		return Range{Base: strings.TrimSpace(string(out)), Head: head, Label: spec}, nil
+	case strings.Contains(spec, ".."):
+		parts := strings.SplitN(spec, "..", 2)
+		r, err := NewRange(repoRoot, orHEAD(parts[0]), orHEAD(parts[1]))
+		r.Label = spec
+		return r, err
+	case spec == "":
+		return Range{}, fmt.Errorf("empty range")
+	default:
+		return ParseRange(repoRoot, spec+"...HEAD")
+	}
+}
+
+// NewRange compares base with head. An empty head compares base with the
+// working tree. A base containing ".." is parsed with ParseRange.
+func NewRange(repoRoot, base, head string) (Range, error) {
+	if strings.Contains(base, "..") {
+		if head != "" {
+			return Range{}, fmt.Errorf("base %q is already a range; drop the head revision", base)
+		}
+		return ParseRange(repoRoot, base)
+	}
+	r := Range{Label: base + ".." + head}
+	var err error
+	if r.Base, err = resolveCommit(repoRoot, base); err != nil {
+		return Range{}, err
+	}
+	if head == "" {
+		r.Label = base + " (working tree)"
+		return r, nil
+	}
+	if r.Head, err = resolveCommit(repoRoot, head); err != nil {
+		return Range{}, err
+	}
+	return r, nil
+}
+
+func orHEAD(rev string) string {
+	if rev == "" {
+		return "HEAD"
+	}
+	return rev
+}
+
+// HasCommit reports whether the repository has the commit id.
+func HasCommit(repoRoot, id string) bool {
+	_, err := resolveCommit(repoRoot, id)
+	return err == nil
+}
+
+func resolveCommit(repoRoot, rev string) (string, error) {
+	out, err := exec.Command("git", "-C", repoRoot, "rev-parse", "--verify", "--quiet", rev+"^{commit}").Output()
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q", rev)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// revs returns the revision arguments for git diff.
+func (r Range) revs() []string {
+	if r.Head == "" {
+		return []string{r.Base}
+	}
+	return []string{r.Base, r.Head}
+}
+
+// RangeFiles lists files changed between the two sides of r, with renames
+// and copies detected. When r compares against the working tree, untracked
+// files are included as well.
+func RangeFiles(repoRoot string, r Range) ([]FileChange, error) {
+	args := append([]string{"--no-optional-locks", "-C", repoRoot, "diff", "--name-status", "-z", "-M", "-C"}, r.revs()...)
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff --name-status: %w", err)
+	}
+	files := parseNameStatus(out)
+	binary, err := binaryPaths(repoRoot, r.revs()...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		files[i].Binary = binary[files[i].Path]
+	}
+	if r.Head == "" {
+		out, err := exec.Command("git", "-C", repoRoot, "ls-files", "--others", "--exclude-standard", "-z").Output()
+		if err != nil {
+			return nil, fmt.Errorf("git ls-files: %w", err)
+		}
+		for _, p := range strings.Split(string(out), "\x00") {
+			if p != "" {
+				files = append(files, FileChange{Path: p, Untracked: true, Added: true, Binary: sniffBinary(filepath.Join(repoRoot, p))})
+			}
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// DiffRange returns the unified diff of one file from RangeFiles.
+func DiffRange(repoRoot string, r Range, f FileChange) (string, error) {
+	if f.Untracked {
+		return DiffHEAD(repoRoot, f.Path)
+	}
+	args := []string{"--literal-pathspecs", "-C", repoRoot, "diff", "--no-color", "--text", "-M", "-C"}
+	if f.Copied {
+		args = append(args, "--find-copies-harder")
+	}
+	args = append(args, r.revs()...)
+	args = append(args, "--")
+	if f.OldPath != "" {
+		args = append(args, f.OldPath)
+	}
+	args = append(args, f.Path)
+	b, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git diff: %w: %s", err, string(b))
+	}
+	return string(b), nil
+}
@@ -0,0 +1,92 @@
+package gitx
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRangeFilesAndDiff(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q", "-b", "main")
+	mustRun(t, dir, "git", "config", "user.email", "test@example.com")
+	mustRun(t, dir, "git", "config", "user.name", "Test User")
+	body := strings.Repeat("line\n", 20)
+	write(t, filepath.Join(dir, "keep.txt"), "one\n")
+	write(t, filepath.Join(dir, "old.go"), body)
+	mustRun(t, dir, "git", "add", ".")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "base")
+
+	mustRun(t, dir, "git", "checkout", "-q", "-b", "feature")
+	mustRun(t, dir, "git", "mv", "old.go", "new.go")
+	write(t, filepath.Join(dir, "new.go"), body+"more\n")
+	write(t, filepath.Join(dir, "added.txt"), "hi\n")
+	write(t, filepath.Join(dir, "keep.txt"), "two\n")
+	mustRun(t, dir, "git", "add", "-A")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "feature work")
+	// main moves on; it must not show up in main...feature
+	mustRun(t, dir, "git", "checkout", "-q", "main")
+	write(t, filepath.Join(dir, "main-only.txt"), "x\n")
+	mustRun(t, dir, "git", "add", ".")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "main work")
+
+	r, err := ParseRange(dir, "main...feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := RangeFiles(dir, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := map[string]FileChange{}
+	for _, f := range files {
+		m[f.Path] = f
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %+v", files)
+	}
+	if !m["added.txt"].Added || m["keep.txt"].Added || !m["new.go"].Renamed || m["new.go"].OldPath != "old.go" {
+		t.Fatalf("unexpected flags: %+v", files)
+	}
+	d, err := DiffRange(dir, r, m["new.go"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(d, "rename from old.go") || !strings.Contains(d, "+more") {
+		t.Fatalf("unexpected diff:\n%s", d)
+	}
+
+	// Two dots compare the tips directly, so main's own commit shows up.
+	r, err = ParseRange(dir, "feature..main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err = RangeFiles(dir, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, f := range files {
+		found = found || f.Path == "main-only.txt"
+	}
+	if !found {
+		t.Fatalf("expected main-only.txt in feature..main: %+v", files)
+	}
+
+	// A base without head compares against the working tree.
+	write(t, filepath.Join(dir, "scratch.txt"), "wip\n")
+	r, err = NewRange(dir, "HEAD", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err = RangeFiles(dir, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "scratch.txt" || !files[0].Untracked {
+		t.Fatalf("expected untracked file against the working tree: %+v", files)
+	}
+	if _, err := NewRange(dir, "no-such-branch", ""); err == nil {
+		t.Fatalf("expected error for unknown revision")
+	}
+}
@@ -0,0 +1,166 @@
+package gitx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// findWorktreeRenames pairs files deleted in the working tree with untracked
+// files that git considers renames or copies of them, so a plain `mv` shows
+// up as one entry instead of a deletion plus a new file. The untracked side
+// carries the source path; a renamed source is dropped from the list.
+func findWorktreeRenames(repoRoot string, files []FileChange) ([]FileChange, error) {
+	var deleted, untracked []string
+	for _, f := range files {
+		switch {
+		case f.Deleted && f.Unstaged && !f.Staged:
+			deleted = append(deleted, f.Path)
+		case f.Untracked:
+			untracked = append(untracked, f.Path)
+		}
+	}
+	if len(deleted) == 0 || len(untracked) == 0 {
+		return files, nil
+	}
+	args := append([]string{"-M", "-C", "--name-status", "-z", "HEAD", "--"}, deleted...)
+	args = append(args, untracked...)
+	out, err := diffWithIntentToAdd(repoRoot, untracked, args...)
+	if err != nil {
+		return nil, err
+	}
+	pairs := parseRenames(out)
+	if len(pairs) == 0 {
+		return files, nil
+	}
+	gone := map[string]bool{}
+	for i := range files {
+		p, ok := pairs[files[i].Path]
+		if !ok || !files[i].Untracked {
+			continue
+		}
+		files[i].OldPath = p.OldPath
+		files[i].Renamed = p.Renamed
+		files[i].Copied = p.Copied
+		files[i].Similarity = p.Similarity
+		files[i].Added = false
+		if p.Renamed {
+			gone[p.OldPath] = true
+		}
+	}
+	kept := files[:0]
+	for _, f := range files {
+		if !gone[f.Path] {
+			kept = append(kept, f)
+		}
+	}
+	return kept, nil
+}
+
+// parseRenames extracts rename and copy pairs, keyed by destination path,
+// from `git diff --name-status -z` output.
+func parseRenames(b []byte) map[string]FileChange {
+	pairs := map[string]FileChange{}
+	for _, fc := range parseNameStatus(b) {
+		if fc.OldPath != "" {
+			pairs[fc.Path] = fc
+		}
+	}
+	return pairs
+}
+
+// parseNameStatus parses `git diff --name-status -z` output. Status letters
+// map onto Added, Deleted, Renamed and Copied; modifications set no flag.
+func parseNameStatus(b []byte) []FileChange {
+	var files []FileChange
+	recs := strings.Split(string(b), "\x00")
+	for i := 0; i+1 < len(recs); i++ {
+		st := recs[i]
+		if st == "" {
+			continue
+		}
+		fc := FileChange{Path: recs[i+1]}
+		i++
+		switch st[0] {
+		case 'A':
+			fc.Added = true
+		case 'D':
+			fc.Deleted = true
+		case 'R', 'C':
+			if i+1 >= len(recs) {
+				return files
+			}
+			fc.OldPath, fc.Path = fc.Path, recs[i+1]
+			i++
+			fc.Renamed = st[0] == 'R'
+			fc.Copied = st[0] == 'C'
+			fc.Similarity, _ = strconv.Atoi(st[1:])
+		}
+		files = append(files, fc)
+	}
+	return files
+}
+
+// diffWithIntentToAdd runs `git diff args...` with the untracked paths
+// registered as intent-to-add in a throwaway index, which lets git pair
+// them with deletions for rename detection. The real index is untouched.
+func diffWithIntentToAdd(repoRoot string, untracked []string, args ...string) ([]byte, error) {
+	var out []byte
+	err := withTempIndex(repoRoot, func(env []string) error {
+		env = append(env, "GIT_LITERAL_PATHSPECS=1")
+		add := exec.Command("git", "-C", repoRoot, "add", "-N", "--pathspec-from-file=-", "--pathspec-file-nul")
+		add.Env = env
+		add.Stdin = strings.NewReader(strings.Join(untracked, "\x00"))
+		if b, err := add.CombinedOutput(); err != nil {
+			return fmt.Errorf("git add -N: %w: %s", err, string(b))
+		}
+		cmd := exec.Command("git", append([]string{"-C", repoRoot, "diff"}, args...)...)
+		cmd.Env = env
+		b, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("git diff: %w", err)
+		}
+		out = b
+		return nil
+	})
+	return out, err
+}
+
+// withTempIndex calls fn with an environment whose GIT_INDEX_FILE points at
+// a copy of the repository's index. The copy is removed afterwards.
+func withTempIndex(repoRoot string, fn func(env []string) error) error {
+	index, err := GitPath(repoRoot, "index")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp("", "diffium-index-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	src, err := os.Open(index)
+	switch {
+	case err == nil:
+		_, err = io.Copy(tmp, src)
+		src.Close()
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+	case os.IsNotExist(err):
+		// No index yet: let git create a fresh one (an empty file is invalid).
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fn(append(os.Environ(), "GIT_INDEX_FILE="+tmp.Name()))
+	default:
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return fn(append(os.Environ(), "GIT_INDEX_FILE="+tmp.Name()))
+}
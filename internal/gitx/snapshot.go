@@ -0,0 +1,146 @@
+package gitx
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SnapshotRef points at the latest snapshot of the working tree.
+const SnapshotRef = "refs/diffium/snapshot"
+
+// Snapshot is a recorded state of the working tree: a commit whose tree
+// holds every file that is not ignored, including untracked ones.
+type Snapshot struct {
+	ID   string // commit id
+	Tree string
+	Time time.Time
+}
+
+// WorktreeTree writes the working tree, including untracked files that are
+// not ignored, as a tree object and returns its id. The index is not
+// touched.
+func WorktreeTree(repoRoot string) (string, error) {
+	return worktreeTree(repoRoot, false)
+}
+
+// worktreeTree is WorktreeTree, with ignored files too when ignored is set.
+func worktreeTree(repoRoot string, ignored bool) (string, error) {
+	args := []string{"-C", repoRoot, "add", "-A"}
+	if ignored {
+		args = append(args, "-f")
+	}
+	var tree string
+	err := withTempIndex(repoRoot, func(env []string) error {
+		add := exec.Command("git", args...)
+		add.Env = env
+		if out, err := add.CombinedOutput(); err != nil {
+			return fmt.Errorf("git add -A: %w: %s", err, out)
+		}
+		wt := exec.Command("git", "-C", repoRoot, "write-tree")
+		wt.Env = env
+		out, err := wt.Output()
+		if err != nil {
+			return fmt.Errorf("git write-tree: %w", err)
+		}
+		tree = strings.TrimSpace(string(out))
+		return nil
+	})
+	return tree, err
+}
+
+// TakeSnapshot records the working tree under SnapshotRef.
+func TakeSnapshot(repoRoot string) (Snapshot, error) {
+	tree, err := WorktreeTree(repoRoot)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var parents []string
+	if head, err := resolveCommit(repoRoot, "HEAD"); err == nil {
+		parents = append(parents, head)
+	}
+	id, err := commitTree(repoRoot, tree, "diffium snapshot", parents...)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if out, err := exec.Command("git", "-C", repoRoot, "update-ref", SnapshotRef, id).CombinedOutput(); err != nil {
+		return Snapshot{}, fmt.Errorf("git update-ref: %w: %s", err, out)
+	}
+	return readSnapshot(repoRoot, id)
+}
+
+// LatestSnapshot returns the snapshot at SnapshotRef; ok is false when none
+// has been taken.
+func LatestSnapshot(repoRoot string) (s Snapshot, ok bool, err error) {
+	id, err := resolveCommit(repoRoot, SnapshotRef)
+	if err != nil {
+		return Snapshot{}, false, nil
+	}
+	s, err = readSnapshot(repoRoot, id)
+	return s, err == nil, err
+}
+
+// SinceSnapshot compares the latest snapshot with the current working tree.
+// Untracked files are included. ok is false when no snapshot has been
+// taken; no ref is written then.
+func SinceSnapshot(repoRoot string) (r Range, ok bool, err error) {
+	s, ok, err := LatestSnapshot(repoRoot)
+	if err != nil || !ok {
+		return Range{}, false, err
+	}
+	tree, err := WorktreeTree(repoRoot)
+	if err != nil {
+		return Range{}, false, err
+	}
+	return Range{Base: s.ID, Head: tree, Label: "since snapshot " + s.Time.Format("15:04:05")}, true, nil
+}
+
+func readSnapshot(repoRoot, id string) (Snapshot, error) {
+	out, err := exec.Command("git", "-C", repoRoot, "show", "-s", "--format="+snapshotFormat, id).Output()
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("git show %s: %w", id, err)
+	}
+	return parseSnapshot(strings.TrimSpace(string(out)))
+}
+
+// snapshotFormat is the git log format parsed by parseSnapshot.
+const snapshotFormat = "%H %T %ct"
+
+func parseSnapshot(line string) (Snapshot, error) {
+	f := strings.Fields(line)
+	if len(f) != 3 {
+		return Snapshot{}, fmt.Errorf("unexpected snapshot line %q", line)
+	}
+	sec, err := strconv.ParseInt(f[2], 10, 64)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("unexpected snapshot line %q", line)
+	}
+	return Snapshot{ID: f[0], Tree: f[1], Time: time.Unix(sec, 0)}, nil
+}
+
+// commitTree creates a commit of tree. The identity is fixed so that
+// snapshots work in repositories without user.name and user.email.
+func commitTree(repoRoot, tree, message string, parents ...string) (string, error) {
+	return commitTreeEnv(repoRoot, tree, message, nil, parents...)
+}
+
+// commitTreeEnv is commitTree with extra environment, such as fixed dates.
+func commitTreeEnv(repoRoot, tree, message string, env []string, parents ...string) (string, error) {
+	args := []string{"-C", repoRoot, "commit-tree", tree, "-m", message}
+	for _, p := range parents {
+		args = append(args, "-p", p)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=diffium", "GIT_AUTHOR_EMAIL=diffium@localhost",
+		"GIT_COMMITTER_NAME=diffium", "GIT_COMMITTER_EMAIL=diffium@localhost")
+	cmd.Env = append(cmd.Env, env...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git commit-tree: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
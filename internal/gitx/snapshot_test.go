@@ -0,0 +1,66 @@
+package gitx
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotAndSinceSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q", "-b", "main")
+	write(t, filepath.Join(dir, "a.txt"), "one\n")
+	write(t, filepath.Join(dir, "new.txt"), "draft\n") // untracked, no commits yet
+
+	if _, ok, err := LatestSnapshot(dir); ok || err != nil {
+		t.Fatalf("expected no snapshot yet, got ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := SinceSnapshot(dir); ok || err != nil {
+		t.Fatalf("expected nothing to compare without a snapshot, got ok=%v err=%v", ok, err)
+	}
+	if _, ok, _ := LatestSnapshot(dir); ok {
+		t.Fatal("expected SinceSnapshot not to take a snapshot")
+	}
+	s, err := TakeSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest, ok, _ := LatestSnapshot(dir); !ok || latest.ID != s.ID {
+		t.Fatalf("expected the snapshot at %s, got %+v", SnapshotRef, latest)
+	}
+
+	// the agent keeps going: edits an untracked file and adds another
+	write(t, filepath.Join(dir, "new.txt"), "final\n")
+	write(t, filepath.Join(dir, "b.txt"), "two\n")
+	r, ok, err := SinceSnapshot(dir)
+	if err != nil || !ok {
+		t.Fatalf("expected changes since the snapshot, got ok=%v err=%v", ok, err)
+	}
+	files, err := RangeFiles(dir, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.Path)
+	}
+	if strings.Join(got, ",") != "b.txt,new.txt" {
+		t.Fatalf("expected only the files changed since the snapshot, got %v", got)
+	}
+	d, err := DiffRange(dir, r, files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(d, "-draft") || !strings.Contains(d, "+final") {
+		t.Fatalf("unexpected diff:\n%s", d)
+	}
+	changed, err := ChangedFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range changed {
+		if !f.Untracked {
+			t.Fatalf("expected the index to be untouched, got %+v", f)
+		}
+	}
+}
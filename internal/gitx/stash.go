@@ -0,0 +1,133 @@
+package gitx
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StashEntry is one entry of `git stash list`.
+type StashEntry struct {
+	Ref     string // stash@{n}
+	Hash    string
+	Date    time.Time
+	Subject string // e.g. "On main: message" or "WIP on main: abc1234 subject"
+	Parents []string
+}
+
+// ListStashes returns the stashes, newest first.
+func ListStashes(repoRoot string) ([]StashEntry, error) {
+	out, err := exec.Command("git", "-C", repoRoot, "stash", "list", "-z", "--format=%gd%x1f%H%x1f%ct%x1f%P%x1f%gs").Output()
+	if err != nil {
+		return nil, fmt.Errorf("git stash list: %w", err)
+	}
+	var list []StashEntry
+	for _, rec := range strings.Split(string(out), "\x00") {
+		rec = strings.TrimPrefix(rec, "\n")
+		if rec == "" {
+			continue
+		}
+		f := strings.SplitN(rec, "\x1f", 5)
+		if len(f) != 5 {
+			return nil, fmt.Errorf("malformed stash record: %q", rec)
+		}
+		secs, _ := strconv.ParseInt(f[2], 10, 64)
+		list = append(list, StashEntry{
+			Ref:     f[0],
+			Hash:    f[1],
+			Date:    time.Unix(secs, 0),
+			Subject: f[4],
+			Parents: strings.Fields(f[3]),
+		})
+	}
+	return list, nil
+}
+
+// Range compares the commit the stash was made on with the stashed working
+// tree. Untracked files stashed with -u live in a third parent; they are
+// merged into the compared tree so they show up as added.
+func (s StashEntry) Range(repoRoot string) (Range, error) {
+	if len(s.Parents) == 0 {
+		return Range{}, fmt.Errorf("%s has no parent", s.Ref)
+	}
+	r := Range{Base: s.Parents[0], Head: s.Hash, Label: s.Ref}
+	if len(s.Parents) < 3 {
+		return r, nil
+	}
+	untracked, err := exec.Command("git", "-C", repoRoot, "ls-tree", "-r", "-z", s.Parents[2]).Output()
+	if err != nil {
+		return Range{}, fmt.Errorf("git ls-tree %s: %w", s.Parents[2], err)
+	}
+	err = withTempIndex(repoRoot, func(env []string) error {
+		rt := exec.Command("git", "-C", repoRoot, "read-tree", s.Hash)
+		rt.Env = env
+		if out, err := rt.CombinedOutput(); err != nil {
+			return fmt.Errorf("git read-tree: %w: %s", err, out)
+		}
+		ui := exec.Command("git", "-C", repoRoot, "update-index", "-z", "--index-info")
+		ui.Env = env
+		ui.Stdin = strings.NewReader(string(untracked))
+		if out, err := ui.CombinedOutput(); err != nil {
+			return fmt.Errorf("git update-index: %w: %s", err, out)
+		}
+		wt := exec.Command("git", "-C", repoRoot, "write-tree")
+		wt.Env = env
+		out, err := wt.Output()
+		if err != nil {
+			return fmt.Errorf("git write-tree: %w", err)
+		}
+		r.Head = strings.TrimSpace(string(out))
+		return nil
+	})
+	return r, err
+}
+
+// StashPush stashes local changes: only paths when given, and untracked
+// files too when untracked is set. An empty message keeps git's default.
+func StashPush(repoRoot, message string, paths []string, untracked bool) error {
+	args := []string{"-C", repoRoot, "stash", "push"}
+	if untracked {
+		args = append(args, "--include-untracked")
+	}
+	if message != "" {
+		args = append(args, "-m", message)
+	}
+	if len(paths) > 0 {
+		args = append(args, "--")
+		args = append(args, paths...)
+	}
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("git stash push: %w: %s", err, out)
+	}
+	return nil
+}
+
+// StashApply applies the stash to the working tree and keeps it.
+func StashApply(repoRoot string, s StashEntry) error {
+	return stashCmd(repoRoot, "apply", s)
+}
+
+// StashPop applies the stash and drops it. git keeps the stash when the
+// apply hits conflicts.
+func StashPop(repoRoot string, s StashEntry) error {
+	return stashCmd(repoRoot, "pop", s)
+}
+
+// StashDrop deletes the stash.
+func StashDrop(repoRoot string, s StashEntry) error {
+	return stashCmd(repoRoot, "drop", s)
+}
+
+// stashCmd runs `git stash <verb> <ref>` after checking that the ref still
+// names the same stash, since refs shift when stashes are added or dropped.
+func stashCmd(repoRoot, verb string, s StashEntry) error {
+	if id, err := resolveCommit(repoRoot, s.Ref); err != nil || id != s.Hash {
+		return fmt.Errorf("%s has changed; reload the stash list", s.Ref)
+	}
+	if out, err := exec.Command("git", "-C", repoRoot, "stash", verb, "-q", s.Ref).CombinedOutput(); err != nil {
+		return fmt.Errorf("git stash %s: %w: %s", verb, err, out)
+	}
+	return nil
+}
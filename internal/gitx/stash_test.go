@@ -0,0 +1,82 @@
+package gitx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStashes(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q", "-b", "main")
+	mustRun(t, dir, "git", "config", "user.email", "test@example.com")
+	mustRun(t, dir, "git", "config", "user.name", "Test User")
+	write(t, filepath.Join(dir, "a.txt"), "v1\n")
+	write(t, filepath.Join(dir, "b.txt"), "v1\n")
+	mustRun(t, dir, "git", "add", ".")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "one")
+
+	if list, err := ListStashes(dir); err != nil || len(list) != 0 {
+		t.Fatalf("expected no stashes, got %+v %v", list, err)
+	}
+	write(t, filepath.Join(dir, "a.txt"), "v2\n")
+	write(t, filepath.Join(dir, "b.txt"), "v2\n")
+	write(t, filepath.Join(dir, "new.txt"), "new\n")
+	if err := StashPush(dir, "only a", []string{"a.txt", "new.txt"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "b.txt")); string(b) != "v2\n" {
+		t.Fatalf("expected b.txt to stay modified, got %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected new.txt to be stashed, got %v", err)
+	}
+
+	list, err := ListStashes(dir)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("expected one stash, got %+v %v", list, err)
+	}
+	s := list[0]
+	if s.Ref != "stash@{0}" || s.Subject != "On main: only a" {
+		t.Fatalf("unexpected stash %+v", s)
+	}
+	r, err := s.Range(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := RangeFiles(dir, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Path != "a.txt" || files[1].Path != "new.txt" || !files[1].Added {
+		t.Fatalf("expected a.txt and the untracked new.txt in the stash, got %+v", files)
+	}
+
+	if err := StashApply(dir, s); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "new.txt")); string(b) != "new\n" {
+		t.Fatalf("expected new.txt back, got %q", b)
+	}
+	mustRun(t, dir, "git", "checkout", "-q", "--", "a.txt")
+	if err := StashPush(dir, "", nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := StashDrop(dir, s); err == nil {
+		t.Fatal("expected a stale stash entry to be refused")
+	}
+	list, _ = ListStashes(dir)
+	if err := StashPop(dir, list[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := StashDrop(dir, list[1]); err == nil {
+		t.Fatal("expected the shifted ref to be refused")
+	}
+	list, _ = ListStashes(dir)
+	if err := StashDrop(dir, list[0]); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ = ListStashes(dir); len(list) != 0 {
+		t.Fatalf("expected all stashes gone, got %+v", list)
+	}
+}
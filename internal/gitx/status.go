@@ -0,0 +1,178 @@
+package gitx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangedFiles lists files changed relative to HEAD, combining staged, unstaged, and untracked.
+// It parses a single `git status --porcelain=v2 -z` and one batched
+// `git diff --numstat` for binary detection. Staged renames and copies come
+// from status; a deletion and an untracked file that form a rename in the
+// working tree are merged into one entry.
+func ChangedFiles(repoRoot string) ([]FileChange, error) {
+	cmd := exec.Command("git", "--no-optional-locks", "-c", "status.renames=copies", "-C", repoRoot, "status", "--porcelain=v2", "-z", "--branch", "--untracked-files=all")
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git status: %w", err)
+	}
+	files, initial, err := parseStatusV2(b)
+	if err != nil {
+		return nil, err
+	}
+	if !initial {
+		if files, err = findWorktreeRenames(repoRoot, files); err != nil {
+			return nil, err
+		}
+	}
+	if err := markBinaries(repoRoot, files, initial); err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// parseStatusV2 parses `git status --porcelain=v2 -z --branch` output. It
+// reports whether the repository has no commits yet.
+func parseStatusV2(b []byte) (files []FileChange, initial bool, err error) {
+	recs := bytes.Split(b, []byte{0})
+	for i := 0; i < len(recs); i++ {
+		rec := string(recs[i])
+		if rec == "" {
+			continue
+		}
+		switch rec[0] {
+		case '#':
+			if rec == "# branch.oid (initial)" {
+				initial = true
+			}
+		case '1', '2', 'u':
+			// "1 XY sub mH mI mW hH hI path"
+			// "2 XY sub mH mI mW hH hI Xscore path\0origPath"
+			// "u XY sub m1 m2 m3 mW h1 h2 h3 path"
+			nFields := map[byte]int{'1': 9, '2': 10, 'u': 11}[rec[0]]
+			parts := strings.SplitN(rec, " ", nFields)
+			if len(parts) != nFields {
+				return nil, false, fmt.Errorf("malformed status record: %q", rec)
+			}
+			xy, sub := parts[1], parts[2]
+			fc := FileChange{Path: parts[nFields-1], Submodule: sub[0] == 'S'}
+			if rec[0] == '2' {
+				score := parts[8]
+				fc.Renamed = score[0] == 'R'
+				fc.Copied = score[0] == 'C'
+				fc.Similarity, _ = strconv.Atoi(score[1:])
+				i++
+				if i < len(recs) {
+					fc.OldPath = string(recs[i])
+				}
+			}
+			if rec[0] == 'u' {
+				fc.Conflicted = true
+				fc.Unstaged = true
+			} else {
+				applyXY(&fc, xy)
+			}
+			files = append(files, fc)
+		case '?':
+			files = append(files, FileChange{Path: rec[2:], Untracked: true, Added: true})
+		}
+	}
+	return files, initial, nil
+}
+
+// applyXY maps the porcelain XY status (index, worktree) onto flags.
+func applyXY(fc *FileChange, xy string) {
+	if len(xy) != 2 {
+		return
+	}
+	x, y := xy[0], xy[1]
+	if x != '.' {
+		fc.Staged = true
+	}
+	if y != '.' {
+		fc.Unstaged = true
+	}
+	if x == 'D' || y == 'D' {
+		fc.Deleted = true
+	}
+	// staged with `git add`, or intent-to-add (`git add -N`)
+	if x == 'A' || y == 'A' {
+		fc.Added = true
+	}
+}
+
+// emptyTree is git's well-known empty tree, used as the base before the
+// first commit.
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// markBinaries flags binary files: tracked ones via one `git diff --numstat`
+// against HEAD, untracked ones by sniffing content like git does.
+func markBinaries(repoRoot string, files []FileChange, initial bool) error {
+	tracked := false
+	for i := range files {
+		if files[i].Untracked {
+			files[i].Binary = sniffBinary(filepath.Join(repoRoot, files[i].Path))
+		} else if !files[i].Submodule {
+			tracked = true
+		}
+	}
+	if !tracked {
+		return nil
+	}
+	base := "HEAD"
+	if initial {
+		base = emptyTree
+	}
+	binary, err := binaryPaths(repoRoot, base)
+	if err != nil {
+		return err
+	}
+	for i := range files {
+		if binary[files[i].Path] {
+			files[i].Binary = true
+		}
+	}
+	return nil
+}
+
+// binaryPaths runs `git diff --numstat` with the given revisions and returns
+// the paths git treats as binary.
+func binaryPaths(repoRoot string, revs ...string) (map[string]bool, error) {
+	args := append([]string{"--no-optional-locks", "-C", repoRoot, "diff", "--numstat", "-z", "--no-renames"}, revs...)
+	b, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff --numstat: %w", err)
+	}
+	binary := map[string]bool{}
+	for _, rec := range strings.Split(string(b), "\x00") {
+		// numstat returns "-\t-\tpath" for binary files
+		parts := strings.SplitN(rec, "\t", 3)
+		if len(parts) == 3 && parts[0] == "-" && parts[1] == "-" {
+			binary[parts[2]] = true
+		}
+	}
+	return binary, nil
+}
+
+// sniffBinary applies git's heuristic: a NUL byte in the first 8000 bytes.
+func sniffBinary(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	buf := make([]byte, 8000)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false
+	}
+	return bytes.IndexByte(buf[:n], 0) >= 0
+}
@@ -0,0 +1,174 @@
+package gitx
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseStatusV2(t *testing.T) {
+	recs := []string{
+		"# branch.oid (initial)",
+		"# branch.head main",
+		"1 .M N... 100644 100644 100644 aaaa aaaa with space.txt",
+		"1 D. N... 100644 000000 000000 aaaa 0000 gone.txt",
+		"2 R. N... 100644 100644 100644 aaaa aaaa R100 new\nline.txt",
+		"old.txt",
+		"u UU N... 100644 100644 100644 100644 aaaa bbbb cccc conflict.txt",
+		"1 .M SC.. 160000 160000 160000 aaaa aaaa vendor/lib",
+		"? untracked.txt",
+	}
+	files, initial, err := parseStatusV2([]byte(strings.Join(recs, "\x00") + "\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !initial {
+		t.Fatalf("expected initial repo to be detected")
+	}
+	m := map[string]FileChange{}
+	for _, f := range files {
+		m[f.Path] = f
+	}
+	if len(files) != 6 {
+		t.Fatalf("expected 6 files, got %+v", files)
+	}
+	if f := m["with space.txt"]; !f.Unstaged || f.Staged {
+		t.Fatalf("unexpected flags for modified file: %+v", f)
+	}
+	if f := m["gone.txt"]; !f.Deleted || !f.Staged || f.Unstaged {
+		t.Fatalf("unexpected flags for staged deletion: %+v", f)
+	}
+	if f := m["new\nline.txt"]; !f.Staged || !f.Renamed || f.OldPath != "old.txt" || f.Similarity != 100 {
+		t.Fatalf("expected staged rename with newline in its name: %+v", f)
+	}
+	if f := m["conflict.txt"]; !f.Conflicted || !f.Unstaged {
+		t.Fatalf("expected conflict flagged: %+v", f)
+	}
+	if f := m["vendor/lib"]; !f.Submodule || !f.Unstaged {
+		t.Fatalf("expected modified submodule: %+v", f)
+	}
+	if f := m["untracked.txt"]; !f.Untracked {
+		t.Fatalf("expected untracked: %+v", f)
+	}
+}
+
+func TestChangedFiles_BinaryAndInitialRepo(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q")
+	mustRun(t, dir, "git", "config", "user.email", "test@example.com")
+	mustRun(t, dir, "git", "config", "user.name", "Test User")
+
+	// Before the first commit: staged text and binary files plus untracked ones.
+	write(t, filepath.Join(dir, "a file.txt"), "text\n")
+	write(t, filepath.Join(dir, "img.bin"), "\x00\x01\x02")
+	mustRun(t, dir, "git", "add", ".")
+	write(t, filepath.Join(dir, "loose.bin"), "x\x00y")
+	write(t, filepath.Join(dir, "loose.txt"), "plain\n")
+
+	files, err := ChangedFiles(dir)
+	if err != nil {
+		t.Fatalf("ChangedFiles error: %v", err)
+	}
+	m := map[string]FileChange{}
+	for _, f := range files {
+		m[f.Path] = f
+	}
+	if f := m["a file.txt"]; !f.Staged || f.Binary {
+		t.Fatalf("unexpected flags: %+v", f)
+	}
+	if !m["img.bin"].Binary || !m["loose.bin"].Binary {
+		t.Fatalf("expected binaries to be detected: %+v", files)
+	}
+	if f := m["loose.txt"]; !f.Untracked || f.Binary {
+		t.Fatalf("unexpected flags: %+v", f)
+	}
+}
+
+func TestChangedFiles_Added(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q")
+	mustRun(t, dir, "git", "config", "user.email", "test@example.com")
+	mustRun(t, dir, "git", "config", "user.name", "Test User")
+	write(t, filepath.Join(dir, "old.txt"), "old\n")
+	mustRun(t, dir, "git", "add", ".")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "init")
+
+	write(t, filepath.Join(dir, "old.txt"), "changed\n")
+	write(t, filepath.Join(dir, "staged.txt"), "new\n")
+	mustRun(t, dir, "git", "add", "staged.txt")
+	write(t, filepath.Join(dir, "loose.txt"), "new\n")
+
+	files, err := ChangedFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := map[string]FileChange{}
+	for _, f := range files {
+		m[f.Path] = f
+	}
+	if f := m["staged.txt"]; !f.Added || !f.Staged {
+		t.Fatalf("expected a staged new file to be added: %+v", f)
+	}
+	if f := m["loose.txt"]; !f.Added || !f.Untracked {
+		t.Fatalf("expected an untracked file to be added: %+v", f)
+	}
+	if f := m["old.txt"]; f.Added {
+		t.Fatalf("expected a modified file not to be added: %+v", f)
+	}
+}
+
+func TestChangedFiles_WorktreeRename(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q")
+	mustRun(t, dir, "git", "config", "user.email", "test@example.com")
+	mustRun(t, dir, "git", "config", "user.name", "Test User")
+	body := strings.Repeat("line\n", 20)
+	write(t, filepath.Join(dir, "old.go"), body)
+	mustRun(t, dir, "git", "add", ".")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "init")
+
+	// Plain mv plus an edit: no git involvement.
+	mustRun(t, dir, "mv", "old.go", "new.go")
+	write(t, filepath.Join(dir, "new.go"), body+"added\n")
+
+	files, err := ChangedFiles(dir)
+	if err != nil {
+		t.Fatalf("ChangedFiles error: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected the move to be a single entry, got %+v", files)
+	}
+	f := files[0]
+	if f.Path != "new.go" || f.OldPath != "old.go" || !f.Renamed || !f.Untracked || f.Added || f.Similarity == 0 {
+		t.Fatalf("unexpected rename entry: %+v", f)
+	}
+	d, err := DiffHEADRenamed(dir, f.OldPath, f.Path)
+	if err != nil {
+		t.Fatalf("DiffHEADRenamed error: %v", err)
+	}
+	if !strings.Contains(d, "rename from old.go") || !strings.Contains(d, "+added") || strings.Contains(d, "-line") {
+		t.Fatalf("expected only the content delta:\n%s", d)
+	}
+	// The real index must be untouched by detection.
+	if out, _ := exec.Command("git", "-C", dir, "diff", "--cached", "--name-only").Output(); len(out) != 0 {
+		t.Fatalf("index modified: %q", out)
+	}
+
+	// Once staged, status reports the rename itself.
+	mustRun(t, dir, "git", "add", "-A")
+	files, err = ChangedFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || !files[0].Renamed || !files[0].Staged || files[0].OldPath != "old.go" {
+		t.Fatalf("unexpected staged rename: %+v", files)
+	}
+	d, err = DiffStagedRenamed(dir, "old.go", "new.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(d, "rename to new.go") || strings.Contains(d, "-line") {
+		t.Fatalf("unexpected staged diff:\n%s", d)
+	}
+}
@@ -0,0 +1,349 @@
+package gitx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Undo journal: before a destructive wizard operation runs, the state needed
+// to reverse it is saved, and once the operation succeeded it is recorded so
+// that Undo can restore it. Saved trees are kept alive by refs under
+// undoRefPrefix; the journal itself lives in the git directory.
+
+const (
+	undoFile      = "diffium/undo.json"
+	undoRefPrefix = "refs/diffium/undo/"
+	undoLimit     = 20 // operations kept in the journal
+)
+
+// Kinds of UndoOp.
+const (
+	UndoReset    = "reset"    // ResetAndClean
+	UndoUncommit = "uncommit" // UncommitFiles
+	UndoCheckout = "checkout" // Checkout
+)
+
+// ErrNothingToUndo is returned by Undo when the journal is empty.
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// UndoOp is a journal entry: an operation and how to reverse it.
+type UndoOp struct {
+	Kind string    `json:"kind"`
+	Time time.Time `json:"time"`
+	Head string    `json:"head,omitempty"` // HEAD before the operation
+	// reset: a commit of the working tree (ignored files too when cleaning
+	// with -x) whose parent, if Index is set, is a commit of the index
+	State string `json:"state,omitempty"`
+	Index bool   `json:"index,omitempty"`
+	Reset bool   `json:"reset,omitempty"`
+	Clean bool   `json:"clean,omitempty"`
+	// uncommit: the paths taken out of the commit at Head
+	Paths []string `json:"paths,omitempty"`
+	// checkout: the branch (or commit, when detached) left and the one
+	// switched to
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+func (op UndoOp) String() string {
+	switch op.Kind {
+	case UndoReset:
+		switch {
+		case op.Reset && op.Clean:
+			return "reset and clean"
+		case op.Reset:
+			return "reset --hard"
+		}
+		return "clean"
+	case UndoUncommit:
+		return "uncommit of " + strings.Join(op.Paths, ", ")
+	case UndoCheckout:
+		return "checkout of " + op.To
+	}
+	return op.Kind
+}
+
+type undoJournal struct {
+	Version int      `json:"version"`
+	Ops     []UndoOp `json:"ops"`
+}
+
+// SaveResetUndo saves the working tree and index before ResetAndClean.
+func SaveResetUndo(repoRoot string, doReset, doClean, includeIgnored bool) (UndoOp, error) {
+	if !doReset && !doClean {
+		return UndoOp{}, nil
+	}
+	head, _ := resolveCommit(repoRoot, "HEAD") // none on an unborn branch
+	tree, err := worktreeTree(repoRoot, doClean && includeIgnored)
+	if err != nil {
+		return UndoOp{}, err
+	}
+	op := UndoOp{Kind: UndoReset, Head: head, Reset: doReset, Clean: doClean}
+	var parents []string
+	// write-tree fails on unmerged entries; the index is then not restored
+	if out, err := exec.Command("git", "-C", repoRoot, "write-tree").Output(); err == nil {
+		id, err := commitTree(repoRoot, strings.TrimSpace(string(out)), "diffium undo: index")
+		if err != nil {
+			return UndoOp{}, err
+		}
+		parents, op.Index = []string{id}, true
+	}
+	if op.State, err = commitTree(repoRoot, tree, "diffium undo: "+op.String(), parents...); err != nil {
+		return UndoOp{}, err
+	}
+	if out, err := exec.Command("git", "-C", repoRoot, "update-ref", undoRefPrefix+op.State, op.State).CombinedOutput(); err != nil {
+		return UndoOp{}, fmt.Errorf("git update-ref: %w: %s", err, out)
+	}
+	return op, nil
+}
+
+// SaveUncommitUndo saves the commit UncommitFiles is about to amend.
+func SaveUncommitUndo(repoRoot string, paths []string) (UndoOp, error) {
+	head, err := resolveCommit(repoRoot, "HEAD")
+	if err != nil {
+		return UndoOp{}, err
+	}
+	return UndoOp{Kind: UndoUncommit, Head: head, Paths: paths}, nil
+}
+
+// SaveCheckoutUndo saves the branch Checkout is about to leave.
+func SaveCheckoutUndo(repoRoot, branch string) (UndoOp, error) {
+	from, err := CurrentBranch(repoRoot)
+	if err != nil {
+		return UndoOp{}, err
+	}
+	if from == "HEAD" {
+		if from, err = resolveCommit(repoRoot, "HEAD"); err != nil {
+			return UndoOp{}, err
+		}
+	}
+	return UndoOp{Kind: UndoCheckout, From: from, To: branch}, nil
+}
+
+// RecordUndo adds a saved operation to the journal once it succeeded. An
+// operation that failed is not recorded, so it cannot stand in the way of
+// undoing older ones; DiscardUndo releases its saved state instead.
+func RecordUndo(repoRoot string, op UndoOp) error {
+	if op.Kind == "" {
+		return nil
+	}
+	return pushUndo(repoRoot, op)
+}
+
+// DiscardUndo releases the state saved for an operation that failed.
+func DiscardUndo(repoRoot string, op UndoOp) {
+	dropUndoRef(repoRoot, op)
+}
+
+// LastUndo returns the operation Undo would reverse; ok is false when there
+// is none.
+func LastUndo(repoRoot string) (op UndoOp, ok bool, err error) {
+	j, _, err := loadUndo(repoRoot)
+	if err != nil || len(j.Ops) == 0 {
+		return UndoOp{}, false, err
+	}
+	return j.Ops[len(j.Ops)-1], true, nil
+}
+
+// Undo reverses the last recorded operation and drops it from the journal.
+// With checkpoint set, reversing a reset first takes a Checkpoint, so the
+// files it overwrites can still be found in the checkpoint timeline.
+func Undo(repoRoot string, checkpoint bool) (UndoOp, error) {
+	j, path, err := loadUndo(repoRoot)
+	if err != nil {
+		return UndoOp{}, err
+	}
+	if len(j.Ops) == 0 {
+		return UndoOp{}, ErrNothingToUndo
+	}
+	op := j.Ops[len(j.Ops)-1]
+	switch op.Kind {
+	case UndoReset:
+		err = undoReset(repoRoot, op, checkpoint)
+	case UndoUncommit:
+		err = undoUncommit(repoRoot, op)
+	case UndoCheckout:
+		err = undoCheckout(repoRoot, op)
+	default:
+		err = fmt.Errorf("unknown operation %q", op.Kind)
+	}
+	if err != nil {
+		return op, fmt.Errorf("undo %s: %w", op, err)
+	}
+	j.Ops = j.Ops[:len(j.Ops)-1]
+	dropUndoRef(repoRoot, op)
+	return op, saveUndo(path, j)
+}
+
+func undoReset(repoRoot string, op UndoOp, checkpoint bool) error {
+	if head, _ := resolveCommit(repoRoot, "HEAD"); op.Reset && head != op.Head {
+		return errors.New("HEAD has moved since")
+	}
+	if checkpoint {
+		if _, _, err := Checkpoint(repoRoot); err != nil {
+			return err
+		}
+	}
+	base, indexRev := op.Head, op.State+"^"
+	if base == "" {
+		base = emptyTree
+	}
+	if !op.Index {
+		indexRev = base
+	}
+	tracked, err := lsTree(repoRoot, indexRev)
+	if err != nil {
+		return err
+	}
+	out, err := exec.Command("git", "-C", repoRoot, "diff-tree", "-r", "-z", "--no-renames", "--name-status", base, op.State).Output()
+	if err != nil {
+		return fmt.Errorf("git diff-tree: %w", err)
+	}
+	// Reset put tracked files back to HEAD, clean removed untracked ones:
+	// restore the former, and the latter where they are still missing.
+	var restore []string
+	f := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	for i := 0; i+1 < len(f); i += 2 {
+		status, p := f[i], f[i+1]
+		_, statErr := os.Lstat(filepath.Join(repoRoot, p))
+		missing := os.IsNotExist(statErr)
+		switch {
+		case status == "D":
+			// deleted before the reset, which brought it back
+			if op.Reset && !missing {
+				if err := os.Remove(filepath.Join(repoRoot, p)); err != nil {
+					return err
+				}
+			}
+		case missing || (op.Reset && tracked[p]):
+			restore = append(restore, p)
+		}
+	}
+	if len(restore) > 0 {
+		err := withTempIndex(repoRoot, func(env []string) error {
+			rt := exec.Command("git", "-C", repoRoot, "read-tree", op.State)
+			rt.Env = env
+			if out, err := rt.CombinedOutput(); err != nil {
+				return fmt.Errorf("git read-tree: %w: %s", err, out)
+			}
+			co := exec.Command("git", "-C", repoRoot, "checkout-index", "-f", "-z", "--stdin")
+			co.Env = env
+			co.Stdin = strings.NewReader(strings.Join(restore, "\x00") + "\x00")
+			if out, err := co.CombinedOutput(); err != nil {
+				return fmt.Errorf("git checkout-index: %w: %s", err, out)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if op.Reset && op.Index {
+		if out, err := exec.Command("git", "-C", repoRoot, "read-tree", indexRev).CombinedOutput(); err != nil {
+			return fmt.Errorf("git read-tree: %w: %s", err, out)
+		}
+		// stat info was lost with read-tree; changed files make this fail
+		_ = exec.Command("git", "-C", repoRoot, "update-index", "-q", "--refresh").Run()
+	}
+	return nil
+}
+
+func undoUncommit(repoRoot string, op UndoOp) error {
+	// HEAD must still be the amended commit, or the commit itself
+	parent, _ := resolveCommit(repoRoot, "HEAD^")
+	want, _ := resolveCommit(repoRoot, op.Head+"^")
+	if parent == "" || parent != want {
+		return errors.New("HEAD has moved since")
+	}
+	if out, err := exec.Command("git", "-C", repoRoot, "reset", "-q", "--soft", op.Head).CombinedOutput(); err != nil {
+		return fmt.Errorf("git reset --soft: %w: %s", err, out)
+	}
+	args := append([]string{"-C", repoRoot, "reset", "-q", op.Head, "--"}, op.Paths...)
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("git reset -- <paths>: %w: %s", err, out)
+	}
+	return nil
+}
+
+func undoCheckout(repoRoot string, op UndoOp) error {
+	cur, err := CurrentBranch(repoRoot)
+	if err != nil {
+		return err
+	}
+	if cur != op.To {
+		return fmt.Errorf("no longer on %s", op.To)
+	}
+	return Checkout(repoRoot, op.From)
+}
+
+func lsTree(repoRoot, rev string) (map[string]bool, error) {
+	out, err := exec.Command("git", "-C", repoRoot, "ls-tree", "-r", "-z", "--name-only", rev).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-tree %s: %w", rev, err)
+	}
+	set := map[string]bool{}
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p != "" {
+			set[p] = true
+		}
+	}
+	return set, nil
+}
+
+func pushUndo(repoRoot string, op UndoOp) error {
+	j, path, err := loadUndo(repoRoot)
+	if err != nil {
+		return err
+	}
+	op.Time = time.Now()
+	j.Ops = append(j.Ops, op)
+	if n := len(j.Ops) - undoLimit; n > 0 {
+		for _, old := range j.Ops[:n] {
+			dropUndoRef(repoRoot, old)
+		}
+		j.Ops = j.Ops[n:]
+	}
+	return saveUndo(path, j)
+}
+
+func dropUndoRef(repoRoot string, op UndoOp) {
+	if op.State != "" {
+		_ = exec.Command("git", "-C", repoRoot, "update-ref", "-d", undoRefPrefix+op.State).Run()
+	}
+}
+
+func loadUndo(repoRoot string) (undoJournal, string, error) {
+	path, err := GitPath(repoRoot, undoFile)
+	if err != nil {
+		return undoJournal{}, "", err
+	}
+	j := undoJournal{Version: 1}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, path, nil
+	}
+	if err != nil {
+		return j, path, err
+	}
+	if err := json.Unmarshal(b, &j); err != nil {
+		return j, path, fmt.Errorf("%s: %w", path, err)
+	}
+	return j, path, nil
+}
+
+func saveUndo(path string, j undoJournal) error {
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
@@ -0,0 +1,115 @@
+package gitx
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q", "-b", "main")
+	mustRun(t, dir, "git", "config", "user.email", "test@example.com")
+	mustRun(t, dir, "git", "config", "user.name", "Test User")
+	write(t, filepath.Join(dir, ".gitignore"), "*.log\n")
+	write(t, filepath.Join(dir, "a.txt"), "v1\n")
+	write(t, filepath.Join(dir, "gone.txt"), "x\n")
+	mustRun(t, dir, "git", "add", ".")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "one")
+
+	write(t, filepath.Join(dir, "a.txt"), "v2\n")
+	write(t, filepath.Join(dir, "staged.txt"), "s\n")
+	mustRun(t, dir, "git", "add", "staged.txt")
+	write(t, filepath.Join(dir, "u.txt"), "u\n")
+	write(t, filepath.Join(dir, "debug.log"), "log\n")
+	if err := os.Remove(filepath.Join(dir, "gone.txt")); err != nil {
+		t.Fatal(err)
+	}
+	before := status(t, dir)
+
+	op, err := SaveResetUndo(dir, true, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ResetAndClean(dir, true, true, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordUndo(dir, op); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(t, dir); got != "" {
+		t.Fatalf("expected a clean tree after reset and clean, got %q", got)
+	}
+	op, err = Undo(dir, true)
+	if err != nil || op.Kind != UndoReset {
+		t.Fatalf("undo reset: %+v %v", op, err)
+	}
+	if got := status(t, dir); got != before {
+		t.Fatalf("expected the status from before the reset\n%s\ngot\n%s", before, got)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "debug.log")); string(b) != "log\n" {
+		t.Fatalf("expected the ignored file back, got %q", b)
+	}
+	if _, err := Undo(dir, true); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("expected nothing left to undo, got %v", err)
+	}
+
+	mustRun(t, dir, "git", "add", "-A")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "two")
+	if err := os.Remove(filepath.Join(dir, "debug.log")); err != nil {
+		t.Fatal(err)
+	}
+	head, _ := resolveCommit(dir, "HEAD")
+	if op, err = SaveUncommitUndo(dir, []string{"a.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := UncommitFiles(dir, []string{"a.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordUndo(dir, op); err != nil {
+		t.Fatal(err)
+	}
+	// a failed checkout is not recorded and leaves the uncommit undoable
+	if op, err = SaveCheckoutUndo(dir, "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Checkout(dir, "missing"); err == nil {
+		t.Fatal("expected checking out a missing branch to fail")
+	}
+	DiscardUndo(dir, op)
+	if op, err := Undo(dir, true); err != nil || op.Kind != UndoUncommit {
+		t.Fatalf("undo uncommit: %+v %v", op, err)
+	}
+	if got, _ := resolveCommit(dir, "HEAD"); got != head || status(t, dir) != "" {
+		t.Fatalf("expected HEAD back at %s with a clean tree, got %s\n%s", head, got, status(t, dir))
+	}
+
+	mustRun(t, dir, "git", "branch", "other")
+	if op, err = SaveCheckoutUndo(dir, "other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Checkout(dir, "other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordUndo(dir, op); err != nil {
+		t.Fatal(err)
+	}
+	if op, err := Undo(dir, true); err != nil || op.Kind != UndoCheckout {
+		t.Fatalf("undo checkout: %+v %v", op, err)
+	}
+	if b, _ := CurrentBranch(dir); b != "main" {
+		t.Fatalf("expected to be back on main, got %s", b)
+	}
+}
+
+func status(t *testing.T, dir string) string {
+	t.Helper()
+	out, err := exec.Command("git", "-C", dir, "status", "--porcelain", "--ignored").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,141 @@
+// Package nvim is a minimal client for Neovim's msgpack-RPC API, used to
+// open files in the editor that diffium runs inside (see $NVIM).
+package nvim
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// timeout bounds a whole call, so a wedged editor cannot hang the TUI.
+const timeout = 5 * time.Second
+
+// Addr is the RPC address of the Neovim instance whose :terminal we run in,
+// or "" when not running inside Neovim.
+func Addr() string {
+	if a := os.Getenv("NVIM"); a != "" {
+		return a
+	}
+	return os.Getenv("NVIM_LISTEN_ADDRESS") // Neovim before 0.7
+}
+
+// Client is a connection to a Neovim instance.
+type Client struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	msgID uint32
+}
+
+// Dial connects to addr: a Unix socket or named pipe path, or host:port.
+func Dial(addr string) (*Client, error) {
+	network := "unix"
+	if !strings.ContainsAny(addr, `/\`) && strings.Contains(addr, ":") {
+		network = "tcp"
+	}
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("connect to nvim: %w", err)
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient wraps an established connection.
+func NewClient(conn net.Conn) *Client {
+	return &Client{conn: conn, r: bufio.NewReader(conn)}
+}
+
+// Close closes the connection.
+func (c *Client) Close() error { return c.conn.Close() }
+
+// Call invokes an API method and returns its result. An error returned by
+// Neovim is reported with its message.
+func (c *Client) Call(method string, args ...any) (any, error) {
+	if args == nil {
+		args = []any{}
+	}
+	c.msgID++
+	id := c.msgID
+	msg, err := encode(nil, []any{0, id, method, args})
+	if err != nil {
+		return nil, err
+	}
+	_ = c.conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := c.conn.Write(msg); err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	for {
+		v, err := decode(c.r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", method, err)
+		}
+		m, ok := v.([]any)
+		if !ok || len(m) != 4 {
+			continue // a notification, or something we do not understand
+		}
+		if kind, _ := m[0].(int64); kind != 1 {
+			continue
+		}
+		if got, _ := m[1].(int64); got != int64(id) {
+			continue
+		}
+		if m[2] != nil {
+			return nil, fmt.Errorf("%s: %s", method, errorMessage(m[2]))
+		}
+		return m[3], nil
+	}
+}
+
+// ExecLua runs a chunk of Lua; args are available to it as "...".
+func (c *Client) ExecLua(code string, args ...any) (any, error) {
+	if args == nil {
+		args = []any{}
+	}
+	return c.Call("nvim_exec_lua", code, args)
+}
+
+// errorMessage extracts the message from Neovim's [type, message] error.
+func errorMessage(v any) string {
+	if e, ok := v.([]any); ok && len(e) == 2 {
+		if s, ok := e[1].(string); ok {
+			return s
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// openFileLua hands the file to the diffium plugin, which knows about the
+// floating terminal, and falls back to editing it in the previous window
+// when the plugin is not loaded. The work is scheduled so the call returns
+// before windows change under the terminal.
+const openFileLua = `
+local path, line = ...
+local ok, diffium = pcall(require, "diffium")
+if ok and type(diffium.open_file) == "function" then
+  diffium.open_file(path, line)
+  return
+end
+vim.schedule(function()
+  vim.cmd("stopinsert")
+  vim.cmd("wincmd p")
+  vim.cmd("edit " .. vim.fn.fnameescape(path))
+  if line > 0 then
+    pcall(vim.api.nvim_win_set_cursor, 0, { line, 0 })
+  end
+end)
+`
+
+// OpenFile asks the Neovim at addr to open path (absolute) at line (1-based;
+// 0 for the top).
+func OpenFile(addr, path string, line int) error {
+	c, err := Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = c.ExecLua(openFileLua, path, line)
+	return err
+}
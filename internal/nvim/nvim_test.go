@@ -0,0 +1,88 @@
+package nvim
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	in := []any{0, 1, -1, -33, 200, 70000, -70000, int64(1) << 40, nil, true, false, 1.5, "", "hi", long,
+		[]any{1, []any{"a"}}, map[string]any{"k": "v"}}
+	b, err := encode(nil, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decode(bufio.NewReader(strings.NewReader(string(b))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []any{int64(0), int64(1), int64(-1), int64(-33), int64(200), int64(70000), int64(-70000), int64(1) << 40,
+		nil, true, false, 1.5, "", "hi", long, []any{int64(1), []any{"a"}}, map[any]any{"k": "v"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip:\n got %#v\nwant %#v", got, want)
+	}
+}
+
+// fakeNvim answers one request on conn after sending a notification, and
+// reports the request it received.
+func fakeNvim(t *testing.T, conn net.Conn, errMsg string) <-chan []any {
+	t.Helper()
+	got := make(chan []any, 1)
+	go func() {
+		defer conn.Close()
+		v, err := decode(bufio.NewReader(conn))
+		if err != nil {
+			close(got)
+			return
+		}
+		req := v.([]any)
+		got <- req
+		var rpcErr any
+		if errMsg != "" {
+			rpcErr = []any{1, errMsg}
+		}
+		note, _ := encode(nil, []any{2, "redraw", []any{}})
+		resp, _ := encode(nil, []any{1, req[1], rpcErr, "ok"})
+		_, _ = conn.Write(append(note, resp...))
+	}()
+	return got
+}
+
+func TestClientExecLua(t *testing.T) {
+	a, b := net.Pipe()
+	got := fakeNvim(t, b, "")
+	c := NewClient(a)
+	defer c.Close()
+
+	res, err := c.ExecLua(openFileLua, "/repo/m.go", 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "ok" {
+		t.Fatalf("result = %#v", res)
+	}
+	req := <-got
+	if req[0] != int64(0) || req[2] != "nvim_exec_lua" {
+		t.Fatalf("request = %#v", req)
+	}
+	params := req[3].([]any)
+	if params[0] != openFileLua || !reflect.DeepEqual(params[1], []any{"/repo/m.go", int64(12)}) {
+		t.Fatalf("params = %#v", params)
+	}
+}
+
+func TestClientError(t *testing.T) {
+	a, b := net.Pipe()
+	fakeNvim(t, b, "E5108: boom")
+	c := NewClient(a)
+	defer c.Close()
+
+	_, err := c.Call("nvim_command", "bad")
+	if err == nil || !strings.Contains(err.Error(), "E5108: boom") {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -15,12 +15,15 @@ type Prefs struct {
 	SideSet    bool
 	LeftWidth  int
 	LeftSet    bool
+	LineNums   bool
+	LineNumSet bool
 }
 
 const (
 	keyWrap       = "diffium.wrap"
 	keySideBySide = "diffium.sideBySide"
 	keyLeftWidth  = "diffium.leftWidth"
+	keyLineNums   = "diffium.lineNumbers"
 )
 
 // Load reads preferences from git local config.
@@ -40,6 +43,10 @@ func Load(repoRoot string) Prefs {
 			p.LeftWidth = n
 		}
 	}
+	if s, ok := get(repoRoot, keyLineNums); ok {
+		p.LineNumSet = true
+		p.LineNums = parseBool(s)
+	}
 	return p
 }
 
@@ -53,6 +60,11 @@ func SaveSideBySide(repoRoot string, v bool) error {
 	return set(repoRoot, keySideBySide, boolStr(v))
 }
 
+// SaveLineNumbers persists the line-number gutter pref.
+func SaveLineNumbers(repoRoot string, v bool) error {
+	return set(repoRoot, keyLineNums, boolStr(v))
+}
+
 // SaveLeftWidth persists left column width.
 func SaveLeftWidth(repoRoot string, w int) error {
 	if w <= 0 {
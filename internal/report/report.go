@@ -0,0 +1,163 @@
+// Package report defines the machine-readable (JSON) form of what diffium
+// shows: the changed files and their parsed diff rows.
+//
+// The schema is versioned. Fields are only added within a version; renaming
+// or removing a field, or changing its meaning, bumps Version.
+package report
+
+import (
+	"github.com/interpretive-systems/diffium/internal/diffview"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+)
+
+// Version is the current schema version.
+const Version = 1
+
+// Status is the output of `diffium status --json`.
+type Status struct {
+	Version int    `json:"version"`
+	Repo    string `json:"repo"`
+	Branch  string `json:"branch,omitempty"`
+	Files   []File `json:"files"`
+}
+
+// Diff is the output of `diffium diff --json`.
+type Diff struct {
+	Version int        `json:"version"`
+	Repo    string     `json:"repo"`
+	Staged  bool       `json:"staged"` // HEAD vs index instead of HEAD vs working tree
+	Files   []FileDiff `json:"files"`
+}
+
+// File is one changed file with its status flags.
+type File struct {
+	Path       string `json:"path"`
+	OldPath    string `json:"oldPath,omitempty"` // source of a rename or copy
+	Staged     bool   `json:"staged"`
+	Unstaged   bool   `json:"unstaged"`
+	Untracked  bool   `json:"untracked"`
+	Added      bool   `json:"added"`
+	Deleted    bool   `json:"deleted"`
+	Renamed    bool   `json:"renamed"`
+	Copied     bool   `json:"copied"`
+	Similarity int    `json:"similarity,omitempty"` // 0-100, for renames and copies
+	Binary     bool   `json:"binary"`
+	Conflicted bool   `json:"conflicted"`
+	Submodule  bool   `json:"submodule"`
+}
+
+// FileDiff is a changed file with the hunks of its diff. Binary files have
+// no hunks.
+type FileDiff struct {
+	File
+	Hunks []Hunk `json:"hunks"`
+}
+
+// Hunk is one "@@" section of a diff.
+type Hunk struct {
+	Header   string `json:"header"`
+	OldStart int    `json:"oldStart"`
+	OldLines int    `json:"oldLines"`
+	NewStart int    `json:"newStart"`
+	NewLines int    `json:"newLines"`
+	Rows     []Row  `json:"rows"`
+}
+
+// Row is one side-by-side row. Old and New are absent on the side without a
+// line; line numbers are 1-based.
+type Row struct {
+	Kind     string  `json:"kind"` // "context", "add", "del" or "replace"
+	OldLine  int     `json:"oldLine,omitempty"`
+	NewLine  int     `json:"newLine,omitempty"`
+	Old      *string `json:"old,omitempty"`
+	New      *string `json:"new,omitempty"`
+	OldNoEOL bool    `json:"oldNoEol,omitempty"` // no newline at end of file
+	NewNoEOL bool    `json:"newNoEol,omitempty"`
+	// Byte ranges [start, end) that changed within a replaced line
+	OldSpans [][2]int `json:"oldSpans,omitempty"`
+	NewSpans [][2]int `json:"newSpans,omitempty"`
+}
+
+// NewFile converts a gitx.FileChange.
+func NewFile(f gitx.FileChange) File {
+	return File{
+		Path:       f.Path,
+		OldPath:    f.OldPath,
+		Staged:     f.Staged,
+		Unstaged:   f.Unstaged,
+		Untracked:  f.Untracked,
+		Added:      f.Added,
+		Deleted:    f.Deleted,
+		Renamed:    f.Renamed,
+		Copied:     f.Copied,
+		Similarity: f.Similarity,
+		Binary:     f.Binary,
+		Conflicted: f.Conflicted,
+		Submodule:  f.Submodule,
+	}
+}
+
+// NewFileDiff converts a file and the rows of its diff.
+func NewFileDiff(f gitx.FileChange, rows []diffview.Row) FileDiff {
+	return FileDiff{File: NewFile(f), Hunks: Hunks(rows)}
+}
+
+// Hunks groups rows (from diffview.BuildRowsFromUnified) by hunk. File
+// header rows are dropped.
+func Hunks(rows []diffview.Row) []Hunk {
+	hunks := []Hunk{}
+	for _, r := range rows {
+		switch r.Kind {
+		case diffview.RowMeta:
+			continue
+		case diffview.RowHunk:
+			h := Hunk{Header: r.Meta, Rows: []Row{}}
+			h.OldStart, h.OldLines, h.NewStart, h.NewLines, _ = diffview.ParseHunkHeader(r.Meta)
+			hunks = append(hunks, h)
+			continue
+		}
+		if len(hunks) == 0 {
+			continue
+		}
+		h := &hunks[len(hunks)-1]
+		h.Rows = append(h.Rows, newRow(r))
+	}
+	return hunks
+}
+
+func newRow(r diffview.Row) Row {
+	out := Row{OldLine: r.LeftLine, NewLine: r.RightLine}
+	hasOld, hasNew := true, true
+	switch r.Kind {
+	case diffview.RowContext:
+		out.Kind = "context"
+	case diffview.RowAdd:
+		out.Kind, hasOld = "add", false
+	case diffview.RowDel:
+		out.Kind, hasNew = "del", false
+	case diffview.RowReplace:
+		out.Kind = "replace"
+		out.OldSpans = spans(r.LeftSpans)
+		out.NewSpans = spans(r.RightSpans)
+	}
+	if hasOld {
+		old := r.Left
+		out.Old, out.OldNoEOL = &old, r.LeftNoEOL
+	}
+	if hasNew {
+		nw := r.Right
+		out.New, out.NewNoEOL = &nw, r.RightNoEOL
+	}
+	return out
+}
+
+func spans(s []diffview.Span) [][2]int {
+	if len(s) == 0 {
+		return nil
+	}
+	out := make([][2]int, len(s))
+	for i, sp := range s {
+		out[i] = [2]int{sp.Start, sp.End}
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package report
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/interpretive-systems/diffium/internal/diffview"
+)
+
+func TestHunks(t *testing.T) {
+	rows := diffview.BuildRowsFromUnified(`diff --git a/f b/f
+--- a/f
++++ b/f
+@@ -3,3 +3,3 @@ func f() {
+ a
+-x = 1
++x = 2
+ b
+@@ -10 +10,2 @@
+ c
++d`)
+	hunks := Hunks(rows)
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %+v", hunks)
+	}
+	h := hunks[0]
+	if h.OldStart != 3 || h.OldLines != 3 || h.NewStart != 3 || h.NewLines != 3 || len(h.Rows) != 3 {
+		t.Fatalf("unexpected first hunk: %+v", h)
+	}
+	r := h.Rows[1]
+	if r.Kind != "replace" || r.OldLine != 4 || r.NewLine != 4 || *r.Old != "x = 1" || *r.New != "x = 2" {
+		t.Fatalf("unexpected replace row: %+v", r)
+	}
+	add := hunks[1].Rows[1]
+	if add.Kind != "add" || add.Old != nil || add.NewLine != 11 {
+		t.Fatalf("unexpected add row: %+v", add)
+	}
+
+	b, err := json.Marshal(hunks[1].Rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"kind":"context","oldLine":10,"newLine":10,"old":"c","new":"c"},{"kind":"add","newLine":11,"new":"d"}]`
+	if got := string(b); got != want {
+		t.Fatalf("unexpected JSON:\n got %s\nwant %s", got, want)
+	}
+}
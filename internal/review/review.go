@@ -0,0 +1,280 @@
+// Package review remembers which files and hunks have been reviewed. A hunk
+// is identified by its path and a hash of its lines, so editing a hunk
+// clears its mark while edits elsewhere in the file (which only shift line
+// numbers) keep it. Marks are kept per scope: the working tree, the index or
+// a commit range.
+package review
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/interpretive-systems/diffium/internal/diffview"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+)
+
+// stateFile is where the state is kept, inside the git directory.
+const stateFile = "diffium/review.json"
+
+// Scopes of marks. A file has different hunks depending on what it is
+// compared with, and marks are only pruned against the diff they were made
+// in, so each comparison keeps its own.
+const (
+	Worktree = "worktree" // HEAD against the working tree
+	Index    = "index"    // HEAD against the index
+)
+
+// RangeScope is the scope of a commit range, given by resolved ids; an empty
+// head is the working tree.
+func RangeScope(base, head string) string {
+	if head == "" {
+		head = Worktree
+	}
+	return base + ".." + head
+}
+
+// Store is the review state of one repository. It is not safe for
+// concurrent use.
+type Store struct {
+	path   string
+	scopes map[string]*Marks
+}
+
+// Marks are the reviewed hunks of one scope.
+type Marks struct {
+	files map[string]map[string]bool // path -> reviewed hunk hashes
+}
+
+type fileFormat struct {
+	Version int                            `json:"version"`
+	Scopes  map[string]map[string][]string `json:"scopes,omitempty"`
+	// version 1 had a single set of marks, pruned against the working tree
+	Files map[string][]string `json:"files,omitempty"`
+}
+
+// Load reads the state of the repository at repoRoot. A missing file is an
+// empty state. A file that cannot be read or parsed also gives an empty
+// store, along with the error; saving that store replaces the file.
+func Load(repoRoot string) (*Store, error) {
+	path, err := gitx.GitPath(repoRoot, stateFile)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{path: path, scopes: map[string]*Marks{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	var f fileFormat
+	if err := json.Unmarshal(b, &f); err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	for p, hashes := range f.Files {
+		s.Scope(Worktree).Mark(p, hashes...)
+	}
+	for name, files := range f.Scopes {
+		for p, hashes := range files {
+			s.Scope(name).Mark(p, hashes...)
+		}
+	}
+	return s, nil
+}
+
+// Save writes the state back.
+func (s *Store) Save() error {
+	f := fileFormat{Version: 2, Scopes: map[string]map[string][]string{}}
+	for name, marks := range s.scopes {
+		if len(marks.files) == 0 {
+			continue
+		}
+		files := map[string][]string{}
+		for p, set := range marks.files {
+			hashes := make([]string, 0, len(set))
+			for h := range set {
+				hashes = append(hashes, h)
+			}
+			sort.Strings(hashes)
+			files[p] = hashes
+		}
+		f.Scopes[name] = files
+	}
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(b, '\n'), 0o644)
+}
+
+// Scope returns the marks of the named scope, which are saved with the
+// store.
+func (s *Store) Scope(name string) *Marks {
+	m := s.scopes[name]
+	if m == nil {
+		m = &Marks{files: map[string]map[string]bool{}}
+		s.scopes[name] = m
+	}
+	return m
+}
+
+// PruneRanges drops the range scopes whose base or head commit is no longer
+// in the repository at repoRoot, e.g. after a branch was deleted and
+// collected. It reports whether any was dropped.
+func (s *Store) PruneRanges(repoRoot string) bool {
+	dropped := false
+	for name := range s.scopes {
+		base, head, ok := strings.Cut(name, "..")
+		if !ok {
+			continue
+		}
+		if !gitx.HasCommit(repoRoot, base) || (head != Worktree && !gitx.HasCommit(repoRoot, head)) {
+			delete(s.scopes, name)
+			dropped = true
+		}
+	}
+	return dropped
+}
+
+// Mark records hunks of path as reviewed.
+func (m *Marks) Mark(path string, hashes ...string) {
+	if len(hashes) == 0 {
+		return
+	}
+	set := m.files[path]
+	if set == nil {
+		set = map[string]bool{}
+		m.files[path] = set
+	}
+	for _, h := range hashes {
+		set[h] = true
+	}
+}
+
+// Unmark forgets hunks of path.
+func (m *Marks) Unmark(path string, hashes ...string) {
+	set := m.files[path]
+	for _, h := range hashes {
+		delete(set, h)
+	}
+	if len(set) == 0 {
+		delete(m.files, path)
+	}
+}
+
+// Reviewed reports whether the hunk is marked.
+func (m *Marks) Reviewed(path, hash string) bool {
+	return m.files[path][hash]
+}
+
+// Has reports whether path has any reviewed hunk.
+func (m *Marks) Has(path string) bool {
+	return len(m.files[path]) > 0
+}
+
+// FileReviewed reports whether a file with the given hunks is fully
+// reviewed. A file without hunks is never reviewed.
+func (m *Marks) FileReviewed(path string, hashes []string) bool {
+	if len(hashes) == 0 {
+		return false
+	}
+	for _, h := range hashes {
+		if !m.Reviewed(path, h) {
+			return false
+		}
+	}
+	return true
+}
+
+// Paths returns the paths with any reviewed hunk.
+func (m *Marks) Paths() []string {
+	out := make([]string, 0, len(m.files))
+	for p := range m.files {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Retain keeps only the hashes of path that are in current, dropping marks
+// of hunks that changed. It reports whether anything was dropped.
+func (m *Marks) Retain(path string, current []string) bool {
+	set := m.files[path]
+	keep := make(map[string]bool, len(current))
+	for _, h := range current {
+		keep[h] = true
+	}
+	dropped := false
+	for h := range set {
+		if !keep[h] {
+			delete(set, h)
+			dropped = true
+		}
+	}
+	if set != nil && len(set) == 0 {
+		delete(m.files, path)
+	}
+	return dropped
+}
+
+// Hashes returns the hash of each hunk in rows (from
+// diffview.BuildRowsFromUnified), in order. A diff without hunks, such as a
+// binary file, gets a single hash of its header so it can still be marked.
+func Hashes(rows []diffview.Row) []string {
+	var out []string
+	for i, r := range rows {
+		if r.Kind != diffview.RowHunk {
+			continue
+		}
+		start, end, _ := diffview.HunkBounds(rows, i)
+		out = append(out, HunkHash(rows, start, end))
+	}
+	if len(out) > 0 {
+		return out
+	}
+	var meta []string
+	for _, r := range rows {
+		if r.Kind == diffview.RowMeta {
+			meta = append(meta, r.Meta)
+		}
+	}
+	if len(meta) == 0 {
+		return nil
+	}
+	return []string{hash(strings.Join(meta, "\n"))}
+}
+
+// HunkHash hashes the lines of the hunk rows[start:end], where start is the
+// hunk header. Line numbers are left out.
+func HunkHash(rows []diffview.Row, start, end int) string {
+	var b strings.Builder
+	for _, r := range rows[start+1 : end] {
+		switch r.Kind {
+		case diffview.RowContext:
+			b.WriteString(" " + r.Left + "\n")
+		case diffview.RowDel:
+			b.WriteString("-" + r.Left + "\n")
+		case diffview.RowAdd:
+			b.WriteString("+" + r.Right + "\n")
+		case diffview.RowReplace:
+			b.WriteString("-" + r.Left + "\n+" + r.Right + "\n")
+		}
+	}
+	return hash(b.String())
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
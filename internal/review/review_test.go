@@ -0,0 +1,172 @@
+package review
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/interpretive-systems/diffium/internal/diffview"
+)
+
+const twoHunks = `--- a/f
++++ b/f
+@@ -1,2 +1,2 @@
+ a
+-b
++B
+@@ -10,2 +10,2 @@
+ j
+-k
++K`
+
+func TestHashesIgnoreLineNumbers(t *testing.T) {
+	h := Hashes(diffview.BuildRowsFromUnified(twoHunks))
+	if len(h) != 2 || h[0] == h[1] {
+		t.Fatalf("expected two distinct hunk hashes, got %v", h)
+	}
+	// the second hunk moved down by two lines: same hash
+	moved := Hashes(diffview.BuildRowsFromUnified("@@ -12,2 +12,2 @@\n j\n-k\n+K\n"))
+	if moved[0] != h[1] {
+		t.Fatal("expected a moved hunk to keep its hash")
+	}
+	// the second hunk changed: new hash
+	edited := Hashes(diffview.BuildRowsFromUnified("@@ -10,2 +10,2 @@\n j\n-k\n+KK\n"))
+	if edited[0] == h[1] {
+		t.Fatal("expected an edited hunk to get a new hash")
+	}
+	binary := Hashes(diffview.BuildRowsFromUnified("diff --git a/x.bin b/x.bin\nindex 1111111..2222222 100644\nBinary files a/x.bin and b/x.bin differ\n"))
+	if len(binary) != 1 {
+		t.Fatalf("expected one hash for a binary diff, got %v", binary)
+	}
+}
+
+func TestStoreSaveLoadRetain(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	s, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := Hashes(diffview.BuildRowsFromUnified(twoHunks))
+	wt := s.Scope(Worktree)
+	wt.Mark("f", h[0])
+	if wt.FileReviewed("f", h) {
+		t.Fatal("file with an unreviewed hunk reported reviewed")
+	}
+	wt.Mark("f", h[1])
+	wt.Mark("g", "stale")
+	s.Scope(RangeScope("abc", "def")).Mark("f", h[0])
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt = s.Scope(Worktree)
+	if !wt.FileReviewed("f", h) || !wt.Reviewed("g", "stale") {
+		t.Fatalf("marks lost across save and load: %v", wt.files)
+	}
+	if !wt.Retain("f", h[1:]) || wt.Reviewed("f", h[0]) || !wt.Reviewed("f", h[1]) {
+		t.Fatal("expected Retain to drop only the changed hunk")
+	}
+	wt.Retain("g", nil)
+	if wt.Has("g") {
+		t.Fatal("expected an unchanged file to lose its marks")
+	}
+	wt.Unmark("f", h[1])
+	if len(wt.Paths()) != 0 {
+		t.Fatalf("expected no marks left, got %v", wt.Paths())
+	}
+	// pruning one scope leaves the others alone
+	if !s.Scope(RangeScope("abc", "def")).Reviewed("f", h[0]) || s.Scope(Index).Has("f") {
+		t.Fatal("expected the range mark kept, and nothing in the index scope")
+	}
+}
+
+func TestLoadVersion1(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	state := filepath.Join(dir, ".git", "diffium")
+	if err := os.MkdirAll(state, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	v1 := `{"version": 1, "files": {"f": ["abc"]}}`
+	if err := os.WriteFile(filepath.Join(state, "review.json"), []byte(v1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Scope(Worktree).Reviewed("f", "abc") {
+		t.Fatal("expected version 1 marks in the worktree scope")
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	state := filepath.Join(dir, ".git", "diffium")
+	if err := os.MkdirAll(state, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(state, "review.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(dir)
+	if err == nil || s == nil || len(s.scopes) != 0 {
+		t.Fatalf("expected an empty store and an error, got %v, %v", s, err)
+	}
+}
+
+func TestPruneRanges(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "one")
+	one := git("rev-parse", "HEAD")
+	git("commit", "-q", "--allow-empty", "-m", "two")
+	two := git("rev-parse", "HEAD")
+	gone := strings.Repeat("0", len(one))
+
+	s, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, scope := range []string{Worktree, RangeScope(one, two), RangeScope(one, ""), RangeScope(gone, two), RangeScope(one, gone)} {
+		s.Scope(scope).Mark("f", "h")
+	}
+	if !s.PruneRanges(dir) {
+		t.Fatal("expected ranges with missing commits dropped")
+	}
+	for _, scope := range []string{RangeScope(gone, two), RangeScope(one, gone)} {
+		if s.scopes[scope] != nil {
+			t.Fatalf("expected %s dropped", scope)
+		}
+	}
+	for _, scope := range []string{Worktree, RangeScope(one, two), RangeScope(one, "")} {
+		if s.scopes[scope] == nil {
+			t.Fatalf("expected %s kept", scope)
+		}
+	}
+	if s.PruneRanges(dir) {
+		t.Fatal("expected nothing left to prune")
+	}
+}
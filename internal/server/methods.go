@@ -0,0 +1,135 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/interpretive-systems/diffium/internal/diffview"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+	"github.com/interpretive-systems/diffium/internal/report"
+)
+
+type diffParams struct {
+	Path   string `json:"path"`
+	Staged bool   `json:"staged"` // HEAD vs index instead of index vs working tree
+}
+
+type stageParams struct {
+	Path   string `json:"path"`
+	Hunk   int    `json:"hunk"`   // 0-based index into getDiff's hunks
+	Staged bool   `json:"staged"` // unstage a hunk of the staged diff
+}
+
+type commitParams struct {
+	Message string   `json:"message"`
+	Paths   []string `json:"paths"` // staged before committing
+	Push    bool     `json:"push"`
+}
+
+type commitResult struct {
+	Summary string `json:"summary"` // "<short hash> <subject>"
+}
+
+// listChanges returns all changed files, as `diffium status --json` does.
+func (s *session) listChanges() (report.Status, error) {
+	files, err := gitx.ChangedFiles(s.repoRoot)
+	if err != nil {
+		return report.Status{}, err
+	}
+	out := report.Status{Version: report.Version, Repo: s.repoRoot, Files: []report.File{}}
+	out.Branch, _ = gitx.CurrentBranch(s.repoRoot)
+	for _, f := range files {
+		out.Files = append(out.Files, report.NewFile(f))
+	}
+	return out, nil
+}
+
+// getDiff returns the hunks of one changed file.
+func (s *session) getDiff(p diffParams) (report.FileDiff, error) {
+	f, rows, err := s.load(p.Path, p.Staged)
+	if err != nil {
+		return report.FileDiff{}, err
+	}
+	return report.NewFileDiff(f, rows), nil
+}
+
+// stageHunk stages one hunk of the unstaged diff, or unstages one of the
+// staged diff.
+func (s *session) stageHunk(p stageParams) error {
+	f, rows, err := s.load(p.Path, p.Staged)
+	if err != nil {
+		return err
+	}
+	n := -1
+	for i, r := range rows {
+		if r.Kind != diffview.RowHunk {
+			continue
+		}
+		if n++; n != p.Hunk {
+			continue
+		}
+		start, end, _ := diffview.HunkBounds(rows, i)
+		patch, err := diffview.BuildPatch(rows, start, end, p.Staged)
+		if err != nil {
+			return err
+		}
+		// as in the TUI: keep a rename out of the patch unless staging a
+		// move that only exists in the working tree
+		if f.OldPath != "" && (p.Staged || !f.Untracked) {
+			patch = diffview.WithoutRename(patch)
+		}
+		if p.Staged {
+			return gitx.UnstagePatch(s.repoRoot, patch)
+		}
+		return gitx.StagePatch(s.repoRoot, patch)
+	}
+	return &Error{Code: codeInvalidParams, Message: fmt.Sprintf("%s has no hunk %d", p.Path, p.Hunk)}
+}
+
+// commit stages the given paths, commits and optionally pushes.
+func (s *session) commit(p commitParams) (commitResult, error) {
+	if err := gitx.StageFiles(s.repoRoot, p.Paths); err != nil {
+		return commitResult{}, err
+	}
+	if err := gitx.Commit(s.repoRoot, p.Message); err != nil {
+		return commitResult{}, err
+	}
+	if p.Push {
+		if err := gitx.Push(s.repoRoot); err != nil {
+			return commitResult{}, err
+		}
+	}
+	summary, err := gitx.LastCommitSummary(s.repoRoot)
+	return commitResult{Summary: summary}, err
+}
+
+// load finds path among the changed files and parses its diff.
+func (s *session) load(path string, staged bool) (gitx.FileChange, []diffview.Row, error) {
+	if path == "" {
+		return gitx.FileChange{}, nil, &Error{Code: codeInvalidParams, Message: "missing path"}
+	}
+	files, err := gitx.ChangedFiles(s.repoRoot)
+	if err != nil {
+		return gitx.FileChange{}, nil, err
+	}
+	for _, f := range files {
+		if f.Path != path {
+			continue
+		}
+		if f.Binary {
+			return f, nil, nil
+		}
+		// Unstaged hunks are taken against the index, not HEAD, so that a
+		// hunk from getDiff applies to the index when staged.
+		var d string
+		if staged || f.Untracked {
+			d, err = gitx.DiffFile(s.repoRoot, f, staged)
+		} else {
+			d, err = gitx.DiffUnstaged(s.repoRoot, f.Path)
+		}
+		if err != nil {
+			return f, nil, err
+		}
+		return f, diffview.BuildRowsFromUnified(d), nil
+	}
+	return gitx.FileChange{}, nil, &Error{Code: codeInvalidParams, Message: fmt.Sprintf("%s has no changes", path)}
+}
@@ -0,0 +1,242 @@
+// Package server exposes diffium over JSON-RPC 2.0 so editors can build
+// native UIs on top of it. Messages are single-line JSON objects separated
+// by newlines, in both directions.
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/interpretive-systems/diffium/internal/gitx"
+	"github.com/interpretive-systems/diffium/internal/watch"
+)
+
+// JSON-RPC error codes.
+const (
+	codeParse          = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeFailed         = -32000 // a git operation failed
+)
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// Options configures a session.
+type Options struct {
+	// Poll checks for changes every second instead of using filesystem
+	// events.
+	Poll bool
+}
+
+// session serves one client.
+type session struct {
+	repoRoot string
+
+	mu  sync.Mutex // serializes writes
+	enc *json.Encoder
+}
+
+// Serve reads requests from r and writes responses and "changed"
+// notifications to w until r is exhausted.
+func Serve(repoRoot string, r io.Reader, w io.Writer, opts Options) error {
+	s := &session{repoRoot: repoRoot, enc: json.NewEncoder(w)}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.watchChanges(stop, opts.Poll)
+	}()
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		s.handle(line)
+	}
+	return sc.Err()
+}
+
+// ListenUnix serves each connection to a Unix socket at path as its own
+// session. An existing socket file is replaced.
+func ListenUnix(repoRoot, path string, opts Options) error {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(path)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			_ = Serve(repoRoot, conn, conn, opts)
+		}()
+	}
+}
+
+func (s *session) handle(line []byte) {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		code := codeParse
+		if json.Valid(line) {
+			code = codeInvalidRequest // e.g. a batch, which is not supported
+		}
+		s.write(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: code, Message: err.Error()}})
+		return
+	}
+	result, err := s.call(req.Method, req.Params)
+	if req.ID == nil {
+		return // a notification from the client; nothing to answer
+	}
+	resp := response{JSONRPC: "2.0", ID: req.ID, Result: result}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: codeFailed, Message: err.Error()}
+		}
+		resp.Result, resp.Error = nil, rpcErr
+	} else if result == nil {
+		resp.Result = struct{}{}
+	}
+	s.write(resp)
+}
+
+func (s *session) call(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "listChanges":
+		return s.listChanges()
+	case "getDiff":
+		var p diffParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.getDiff(p)
+	case "stageHunk":
+		var p stageParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return nil, s.stageHunk(p)
+	case "commit":
+		var p commitParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.commit(p)
+	}
+	return nil, &Error{Code: codeMethodNotFound, Message: fmt.Sprintf("unknown method %q", method)}
+}
+
+func decodeParams(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &Error{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *session) write(v any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.enc.Encode(v)
+}
+
+// watchChanges sends a "changed" notification whenever the working tree or
+// index changes, until stop is closed.
+func (s *session) watchChanges(stop <-chan struct{}, poll bool) {
+	if !poll {
+		if w, err := watch.New(s.repoRoot); err == nil {
+			defer w.Close()
+			for {
+				select {
+				case <-stop:
+					return
+				case _, ok := <-w.Events:
+					if !ok {
+						// the watcher failed; keep notifying by polling
+						s.pollChanges(stop)
+						return
+					}
+					s.notify()
+				}
+			}
+		}
+	}
+	s.pollChanges(stop)
+}
+
+func (s *session) pollChanges(stop <-chan struct{}) {
+	last, _ := gitx.ChangedFiles(s.repoRoot)
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			files, err := gitx.ChangedFiles(s.repoRoot)
+			if err != nil || reflect.DeepEqual(files, last) {
+				continue
+			}
+			last = files
+			s.notify()
+		}
+	}
+}
+
+func (s *session) notify() {
+	s.write(notification{JSONRPC: "2.0", Method: "changed", Params: struct{}{}})
+}
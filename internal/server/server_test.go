@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func mustRun(t *testing.T, dir string, name string, args ...string) {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("command %s %v failed: %v\n%s", name, args, err, out)
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type reply struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// roundTrip serves the given request lines and returns the replies by id.
+func roundTrip(t *testing.T, dir string, lines ...string) map[string]reply {
+	t.Helper()
+	var out strings.Builder
+	if err := Serve(dir, strings.NewReader(strings.Join(lines, "\n")+"\n"), &out, Options{Poll: true}); err != nil {
+		t.Fatal(err)
+	}
+	replies := map[string]reply{}
+	sc := bufio.NewScanner(strings.NewReader(out.String()))
+	for sc.Scan() {
+		var r reply
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("bad output line %q: %v", sc.Text(), err)
+		}
+		if r.ID != nil { // skip notifications
+			replies[string(r.ID)] = r
+		}
+	}
+	return replies
+}
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q")
+	mustRun(t, dir, "git", "config", "user.email", "test@example.com")
+	mustRun(t, dir, "git", "config", "user.name", "Test User")
+	write(t, filepath.Join(dir, "f.txt"), "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n")
+	write(t, filepath.Join(dir, "g.txt"), "a\nb\nc\nd\ne\n")
+	mustRun(t, dir, "git", "add", ".")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "init")
+	write(t, filepath.Join(dir, "f.txt"), "A\nb\nc\nd\ne\nf\ng\nh\ni\nJ\n")
+	// g.txt is partially staged: B is in the index, C next to it is not
+	write(t, filepath.Join(dir, "g.txt"), "a\nB\nc\nd\ne\n")
+	mustRun(t, dir, "git", "add", "g.txt")
+	write(t, filepath.Join(dir, "g.txt"), "a\nB\nC\nd\ne\n")
+
+	resps := roundTrip(t, dir,
+		`{"jsonrpc":"2.0","id":1,"method":"listChanges"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"getDiff","params":{"path":"f.txt"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"stageHunk","params":{"path":"f.txt","hunk":1}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"stageHunk","params":{"path":"f.txt","hunk":5}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"bogus"}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":6,"method":"getDiff","params":{"path":"g.txt"}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"stageHunk","params":{"path":"g.txt","hunk":0}}`,
+	)
+	if got := string(resps["1"].Result); !strings.Contains(got, `"path":"f.txt"`) {
+		t.Fatalf("listChanges: %s", got)
+	}
+	var diff struct {
+		Hunks []struct {
+			Rows []struct{ Kind string } `json:"rows"`
+		} `json:"hunks"`
+	}
+	if err := json.Unmarshal(resps["2"].Result, &diff); err != nil || len(diff.Hunks) != 2 {
+		t.Fatalf("getDiff: %v %+v", err, diff)
+	}
+	if resps["3"].Error != nil {
+		t.Fatalf("stageHunk: %+v", resps["3"].Error)
+	}
+	if e := resps["4"].Error; e == nil || e.Code != codeInvalidParams {
+		t.Fatalf("expected invalid params for a missing hunk, got %+v", e)
+	}
+	if e := resps["5"].Error; e == nil || e.Code != codeMethodNotFound {
+		t.Fatalf("expected method not found, got %+v", e)
+	}
+	if e := resps["null"].Error; e == nil || e.Code != codeParse {
+		t.Fatalf("expected a parse error, got %+v", e)
+	}
+
+	// The unstaged diff of g.txt holds only C, and staging it applies.
+	if got := string(resps["6"].Result); !strings.Contains(got, `"new":"C"`) || strings.Contains(got, `"old":"b"`) {
+		t.Fatalf("getDiff g.txt: %s", got)
+	}
+	if resps["7"].Error != nil {
+		t.Fatalf("stageHunk g.txt: %+v", resps["7"].Error)
+	}
+
+	// Only the second hunk of f.txt was staged, and all of g.txt.
+	out, err := exec.Command("git", "-C", dir, "diff", "--cached").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "+J") || strings.Contains(string(out), "+A") ||
+		!strings.Contains(string(out), "+B") || !strings.Contains(string(out), "+C") {
+		t.Fatalf("unexpected staged diff:\n%s", out)
+	}
+	if out, err := exec.Command("git", "-C", dir, "diff", "--", "g.txt").Output(); err != nil || len(out) > 0 {
+		t.Fatalf("g.txt still has unstaged changes: %v\n%s", err, out)
+	}
+}
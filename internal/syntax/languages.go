@@ -0,0 +1,273 @@
+package syntax
+
+import (
+	"strings"
+	"sync"
+)
+
+// Delim describes a string literal.
+type Delim struct {
+	Open, Close string
+	Escape      bool // backslash escapes the next character
+	MultiLine   bool // may span lines
+}
+
+// Language holds the lexical rules of one language.
+type Language struct {
+	Name          string
+	Keywords      []string
+	Types         []string
+	LineComments  []string
+	BlockComments [][2]string
+	Strings       []Delim // longer openers first
+
+	once     sync.Once
+	keywords map[string]bool
+	types    map[string]bool
+}
+
+func (l *Language) init() {
+	l.once.Do(func() {
+		l.keywords = make(map[string]bool, len(l.Keywords))
+		for _, w := range l.Keywords {
+			l.keywords[w] = true
+		}
+		l.types = make(map[string]bool, len(l.Types))
+		for _, w := range l.Types {
+			l.types[w] = true
+		}
+	})
+}
+
+// comment reports whether a comment starts at src[i] and where it ends.
+func (l *Language) comment(src string, i int) (int, bool) {
+	rest := src[i:]
+	for _, c := range l.BlockComments {
+		if strings.HasPrefix(rest, c[0]) {
+			if end := strings.Index(rest[len(c[0]):], c[1]); end >= 0 {
+				return i + len(c[0]) + end + len(c[1]), true
+			}
+			return len(src), true
+		}
+	}
+	for _, c := range l.LineComments {
+		if !strings.HasPrefix(rest, c) {
+			continue
+		}
+		// "#" only starts a comment at a word boundary ($#, a#b in shell)
+		if c == "#" && i > 0 && !strings.ContainsRune(" \t\n(;", rune(src[i-1])) {
+			continue
+		}
+		if end := strings.IndexByte(rest, '\n'); end >= 0 {
+			return i + end, true
+		}
+		return len(src), true
+	}
+	return 0, false
+}
+
+// str reports whether a string literal starts at src[i] and where it ends.
+// An unterminated single-line string ends at the end of the line.
+func (l *Language) str(src string, i int) (int, bool) {
+	for _, d := range l.Strings {
+		if !strings.HasPrefix(src[i:], d.Open) {
+			continue
+		}
+		j := i + len(d.Open)
+		for j < len(src) {
+			switch {
+			case d.Escape && src[j] == '\\':
+				j += 2
+				continue
+			case strings.HasPrefix(src[j:], d.Close):
+				return j + len(d.Close), true
+			case src[j] == '\n' && !d.MultiLine:
+				return j, true
+			}
+			j++
+		}
+		return len(src), true
+	}
+	return 0, false
+}
+
+var (
+	dq        = Delim{Open: `"`, Close: `"`, Escape: true}
+	sq        = Delim{Open: `'`, Close: `'`, Escape: true}
+	cComments = [][2]string{{"/*", "*/"}}
+)
+
+var golang = &Language{
+	Name: "go",
+	Keywords: words(`break case chan const continue default defer else fallthrough
+		for func go goto if import interface map package range return select
+		struct switch type var true false nil iota`),
+	Types: words(`any bool byte comparable complex64 complex128 error float32
+		float64 int int8 int16 int32 int64 rune string uint uint8 uint16 uint32
+		uint64 uintptr`),
+	LineComments:  []string{"//"},
+	BlockComments: cComments,
+	Strings:       []Delim{dq, sq, {Open: "`", Close: "`", MultiLine: true}},
+}
+
+var python = &Language{
+	Name: "python",
+	Keywords: words(`and as assert async await break class continue def del elif
+		else except finally for from global if import in is lambda nonlocal not
+		or pass raise return try while with yield match case True False None self`),
+	Types:        words(`bool bytes dict float frozenset int list object set str tuple type`),
+	LineComments: []string{"#"},
+	Strings: []Delim{
+		{Open: `"""`, Close: `"""`, Escape: true, MultiLine: true},
+		{Open: `'''`, Close: `'''`, Escape: true, MultiLine: true},
+		dq, sq,
+	},
+}
+
+var javascript = &Language{
+	Name: "javascript",
+	Keywords: words(`async await break case catch class const continue debugger
+		default delete do else export extends finally for from function if import
+		in instanceof let new of return static super switch this throw try typeof
+		var void while with yield true false null undefined`),
+	Types:         words(`Array Boolean Date Error Map Number Object Promise Set String`),
+	LineComments:  []string{"//"},
+	BlockComments: cComments,
+	Strings:       []Delim{dq, sq, {Open: "`", Close: "`", Escape: true, MultiLine: true}},
+}
+
+var typescript = &Language{
+	Name: "typescript",
+	Keywords: append(words(`abstract as declare enum implements interface keyof
+		namespace private protected public readonly type`), javascript.Keywords...),
+	Types: append(words(`any boolean never number string symbol unknown void bigint`),
+		javascript.Types...),
+	LineComments:  []string{"//"},
+	BlockComments: cComments,
+	Strings:       javascript.Strings,
+}
+
+var rust = &Language{
+	Name: "rust",
+	Keywords: words(`as async await break const continue crate dyn else enum extern
+		fn for if impl in let loop match mod move mut pub ref return self Self
+		static struct super trait type unsafe use where while true false`),
+	Types: words(`bool char f32 f64 i8 i16 i32 i64 i128 isize str u8 u16 u32 u64
+		u128 usize String Vec Option Result Box`),
+	LineComments:  []string{"//"},
+	BlockComments: cComments,
+	Strings:       []Delim{{Open: `"`, Close: `"`, Escape: true, MultiLine: true}},
+}
+
+var clang = &Language{
+	Name: "c",
+	Keywords: words(`auto break case const continue default do else enum extern for
+		goto if inline register restrict return sizeof static struct switch
+		typedef union volatile while NULL true false
+		class namespace template typename public private protected virtual
+		override new delete this throw try catch using nullptr`),
+	Types: words(`bool char double float int long short signed unsigned void
+		size_t ssize_t int8_t int16_t int32_t int64_t uint8_t uint16_t uint32_t
+		uint64_t`),
+	LineComments:  []string{"//"},
+	BlockComments: cComments,
+	Strings:       []Delim{dq, sq},
+}
+
+var java = &Language{
+	Name: "java",
+	Keywords: words(`abstract assert break case catch class const continue default
+		do else enum extends final finally for if implements import instanceof
+		interface native new package private protected public return static super
+		switch synchronized this throw throws transient try var volatile while
+		record true false null fun val when object companion data override`),
+	Types:         words(`boolean byte char double float int long short void String Object Integer`),
+	LineComments:  []string{"//"},
+	BlockComments: cComments,
+	Strings:       []Delim{{Open: `"""`, Close: `"""`, Escape: true, MultiLine: true}, dq, sq},
+}
+
+var shell = &Language{
+	Name: "shell",
+	Keywords: words(`if then else elif fi case esac for while until do done in
+		function return local export readonly set unset shift exit break continue`),
+	LineComments: []string{"#"},
+	Strings: []Delim{
+		{Open: `"`, Close: `"`, Escape: true, MultiLine: true},
+		{Open: `'`, Close: `'`, MultiLine: true},
+	},
+}
+
+var ruby = &Language{
+	Name: "ruby",
+	Keywords: words(`alias and begin break case class def defined do else elsif end
+		ensure false for if in module next nil not or redo rescue retry return
+		self super then true undef unless until when while yield require`),
+	LineComments: []string{"#"},
+	Strings:      []Delim{dq, sq},
+}
+
+var jsonLang = &Language{
+	Name:     "json",
+	Keywords: words(`true false null`),
+	Strings:  []Delim{dq},
+}
+
+var yaml = &Language{
+	Name:         "yaml",
+	Keywords:     words(`true false null yes no on off`),
+	LineComments: []string{"#"},
+	Strings:      []Delim{dq, {Open: `'`, Close: `'`}},
+}
+
+var byExt = map[string]*Language{
+	".go":   golang,
+	".py":   python,
+	".pyi":  python,
+	".js":   javascript,
+	".mjs":  javascript,
+	".cjs":  javascript,
+	".jsx":  javascript,
+	".ts":   typescript,
+	".tsx":  typescript,
+	".rs":   rust,
+	".c":    clang,
+	".h":    clang,
+	".cc":   clang,
+	".cpp":  clang,
+	".cxx":  clang,
+	".hpp":  clang,
+	".java": java,
+	".kt":   java,
+	".sh":   shell,
+	".bash": shell,
+	".zsh":  shell,
+	".rb":   ruby,
+	".json": jsonLang,
+	".yaml": yaml,
+	".yml":  yaml,
+}
+
+var byName = map[string]*Language{
+	"Rakefile": ruby,
+	"Gemfile":  ruby,
+	".bashrc":  shell,
+	".zshrc":   shell,
+	".profile": shell,
+}
+
+var byInterp = map[string]*Language{
+	"sh":     shell,
+	"bash":   shell,
+	"zsh":    shell,
+	"dash":   shell,
+	"ksh":    shell,
+	"python": python,
+	"node":   javascript,
+	"deno":   typescript,
+	"ruby":   ruby,
+}
+
+func words(s string) []string {
+	return strings.Fields(s)
+}
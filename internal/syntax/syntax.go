@@ -0,0 +1,142 @@
+// Package syntax is a small, language-aware highlighter for diff text. It
+// recognizes keywords, types, strings, comments and numbers for common
+// languages; it does not try to be a full parser.
+package syntax
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind classifies a highlighted token.
+type Kind int
+
+const (
+	Plain Kind = iota
+	Keyword
+	Type
+	String
+	Comment
+	Number
+)
+
+// Span marks bytes [Start, End) of a line as one kind of token.
+type Span struct {
+	Start, End int
+	Kind       Kind
+}
+
+// Detect picks a language from the file name, falling back to a "#!" line.
+// It returns nil for unknown files.
+func Detect(path, firstLine string) *Language {
+	base := filepath.Base(path)
+	if l, ok := byName[base]; ok {
+		return l
+	}
+	if l, ok := byExt[strings.ToLower(filepath.Ext(base))]; ok {
+		return l
+	}
+	if !strings.HasPrefix(firstLine, "#!") {
+		return nil
+	}
+	fields := strings.Fields(strings.TrimPrefix(firstLine, "#!"))
+	if len(fields) == 0 {
+		return nil
+	}
+	interp := filepath.Base(fields[0])
+	if interp == "env" {
+		interp = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				interp = f
+				break
+			}
+		}
+	}
+	// python3.12 -> python, bash5 -> bash
+	interp = strings.TrimRight(interp, "0123456789.")
+	return byInterp[interp]
+}
+
+// Highlight tokenizes src as a whole, so strings and comments spanning
+// several lines are classified correctly, and returns the spans of each line
+// (index 0 is line 1). Offsets are relative to the start of the line and
+// plain text has no span.
+func Highlight(lang *Language, src string) [][]Span {
+	if lang == nil {
+		return nil
+	}
+	starts := []int{0} // byte offset of each line
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	lines := make([][]Span, len(starts))
+	line := 0
+	// add records [start, end) of src, split at line breaks; calls come in
+	// increasing order of start.
+	add := func(start, end int, kind Kind) {
+		for start < end {
+			for line+1 < len(starts) && starts[line+1] <= start {
+				line++
+			}
+			stop := end
+			if line+1 < len(starts) && starts[line+1]-1 < end {
+				stop = starts[line+1] - 1
+			}
+			if stop > start {
+				lines[line] = append(lines[line], Span{Start: start - starts[line], End: stop - starts[line], Kind: kind})
+			}
+			start = stop + 1
+		}
+	}
+	lang.init()
+	for i := 0; i < len(src); {
+		if end, ok := lang.comment(src, i); ok {
+			add(i, end, Comment)
+			i = end
+			continue
+		}
+		if end, ok := lang.str(src, i); ok {
+			add(i, end, String)
+			i = end
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(src[i:])
+		if isWord(r) && !isWordBefore(src, i) {
+			end := i + size
+			for end < len(src) {
+				r2, sz := utf8.DecodeRuneInString(src[end:])
+				if !isWord(r2) && !(unicode.IsDigit(r) && r2 == '.') {
+					break
+				}
+				end += sz
+			}
+			word := src[i:end]
+			switch {
+			case unicode.IsDigit(r):
+				add(i, end, Number)
+			case lang.keywords[word]:
+				add(i, end, Keyword)
+			case lang.types[word]:
+				add(i, end, Type)
+			}
+			i = end
+			continue
+		}
+		i += size
+	}
+	return lines
+}
+
+func isWord(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isWordBefore(src string, i int) bool {
+	r, _ := utf8.DecodeLastRuneInString(src[:i])
+	return isWord(r)
+}
@@ -0,0 +1,57 @@
+package syntax
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		path, first, want string
+	}{
+		{"main.go", "", "go"},
+		{"web/App.TSX", "", "typescript"},
+		{"scripts/build", "#!/usr/bin/env bash", "shell"},
+		{"tool", "#!/usr/bin/python3.12 -u", "python"},
+		{"notes.txt", "", ""},
+	}
+	for _, c := range cases {
+		got := ""
+		if l := Detect(c.path, c.first); l != nil {
+			got = l.Name
+		}
+		if got != c.want {
+			t.Errorf("Detect(%q, %q) = %q, want %q", c.path, c.first, got, c.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	src := "func f() int {\n\t/* a\n\tb */ s := `x\ny` // done\n\treturn 42\n}"
+	lines := Highlight(Detect("f.go", ""), src)
+	if len(lines) != 6 {
+		t.Fatalf("expected 6 lines, got %d", len(lines))
+	}
+	text := []string{"func f() int {", "\t/* a", "\tb */ s := `x", "y` // done", "\treturn 42"}
+	want := [][]string{
+		{"func", "int"},
+		{"/* a"},
+		{"\tb */", "`x"},
+		{"y`", "// done"},
+		{"return", "42"},
+	}
+	kinds := [][]Kind{
+		{Keyword, Type},
+		{Comment},
+		{Comment, String},
+		{String, Comment},
+		{Keyword, Number},
+	}
+	for i, spans := range lines[:5] {
+		if len(spans) != len(want[i]) {
+			t.Fatalf("line %d: got %+v", i+1, spans)
+		}
+		for k, sp := range spans {
+			if got := text[i][sp.Start:sp.End]; got != want[i][k] || sp.Kind != kinds[i][k] {
+				t.Errorf("line %d span %d: got %q (%d), want %q (%d)", i+1, k, got, sp.Kind, want[i][k], kinds[i][k])
+			}
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/interpretive-systems/diffium/internal/diffview"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+)
+
+// --- Merge conflict view: ours/base/theirs per block ---
+
+type conflictMsg struct {
+	path string
+	file *diffview.ConflictFile
+	err  error
+}
+
+// loadConflict reads a conflicted file from the working tree. When it was
+// written without a base section, the bases come from a diff3 re-merge of
+// the index stages.
+func loadConflict(repoRoot, path string) tea.Cmd {
+	return func() tea.Msg {
+		b, err := os.ReadFile(filepath.Join(repoRoot, path))
+		if err != nil && !os.IsNotExist(err) {
+			return conflictMsg{path: path, err: err}
+		}
+		f := diffview.ParseConflicts(string(b))
+		for _, blk := range f.Blocks() {
+			if !blk.BaseKnown {
+				if d3, err := gitx.ConflictDiff3(repoRoot, path); err == nil {
+					f.BorrowBase(diffview.ParseConflicts(d3))
+				}
+				break
+			}
+		}
+		return conflictMsg{path: path, file: f}
+	}
+}
+
+// conflictView reports whether the right pane shows the three-way view.
+func (m model) conflictView() bool {
+	if m.conflict == nil || len(m.files) == 0 || m.diffMode == "staged" {
+		return false
+	}
+	f := m.files[m.selected]
+	return f.Conflicted && f.Path == m.conflictPath
+}
+
+func (m model) conflictResult(msg conflictMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		m.status = fmt.Sprintf("conflict error: %v", msg.err)
+		return m, m.recalcViewport()
+	}
+	if len(m.files) == 0 || m.files[m.selected].Path != msg.path {
+		return m, nil
+	}
+	if m.conflictPath != msg.path {
+		m.conflictPath = msg.path
+		m.diffCursor = 0
+	}
+	m.conflict = msg.file
+	m.rowsPath = ""
+	if n := len(m.conflict.Blocks()); m.diffCursor >= n {
+		m.diffCursor = n - 1
+	}
+	if m.diffCursor < 0 {
+		m.diffCursor = 0
+	}
+	return m, m.recalcViewport()
+}
+
+// handleConflictKeys handles keys while the conflict view has focus. Keys it
+// does not handle fall through to the normal bindings.
+func (m model) handleConflictKeys(key tea.KeyMsg) (model, tea.Cmd, bool) {
+	blocks := m.conflict.Blocks()
+	switch key.String() {
+	case "tab", "esc":
+		m.diffFocus = false
+		return m, m.recalcViewport(), true
+	case "j", "down", "]":
+		if m.diffCursor < len(blocks)-1 {
+			m.diffCursor++
+		}
+	case "k", "up", "[":
+		if m.diffCursor > 0 {
+			m.diffCursor--
+		}
+	case "g":
+		m.diffCursor = 0
+	case "G":
+		m.diffCursor = len(blocks) - 1
+	case "o":
+		return m, m.takeConflictSide(diffview.TakeOurs), true
+	case "t":
+		return m, m.takeConflictSide(diffview.TakeTheirs), true
+	case "a":
+		if len(blocks) > 0 {
+			m.status = fmt.Sprintf("%d conflict(s) left in %s", len(blocks), m.conflictPath)
+			return m, nil, true
+		}
+		m.diffFocus = false
+		repoRoot, path := m.repoRoot, m.conflictPath
+		return m, func() tea.Msg {
+			return patchResultMsg{done: "marked " + path + " resolved", err: gitx.StageFiles(repoRoot, []string{path})}
+		}, true
+	default:
+		return m, nil, false
+	}
+	m.recalcViewport()
+	m.ensureCursorVisible()
+	return m, nil, true
+}
+
+// takeConflictSide replaces the block under the cursor with one side and
+// writes the file back.
+func (m *model) takeConflictSide(side diffview.ConflictSide) tea.Cmd {
+	if m.diffCursor < 0 || m.diffCursor >= len(m.conflict.Blocks()) {
+		m.status = "no conflict under cursor"
+		return nil
+	}
+	content := m.conflict.Resolve(m.diffCursor, side)
+	what := "ours"
+	if side == diffview.TakeTheirs {
+		what = "theirs"
+	}
+	done := fmt.Sprintf("took %s for conflict %d", what, m.diffCursor+1)
+	full := filepath.Join(m.repoRoot, m.conflictPath)
+	return func() tea.Msg {
+		return patchResultMsg{done: done, err: os.WriteFile(full, []byte(content), 0o644)}
+	}
+}
+
+// conflictLines renders the conflict view and returns the first line of
+// each block.
+func (m model) conflictLines(width int) ([]string, []int) {
+	faint := lipgloss.NewStyle().Faint(true)
+	blocks := m.conflict.Blocks()
+	if len(blocks) == 0 {
+		return []string{faint.Render("No conflict markers left. Press tab, then a to mark the file resolved (git add).")}, nil
+	}
+	colW := (width - 2) / 3
+	if colW < 10 {
+		colW = 10
+	}
+	mid := m.theme.DividerText("│")
+	oursStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+	theirsStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("170"))
+	cell := func(lines []string, i int, style lipgloss.Style) string {
+		if i >= len(lines) {
+			return strings.Repeat(" ", colW)
+		}
+		return padExact(ansi.Truncate(style.Render(strings.TrimRight(lines[i], "\r\n")), colW, "…"), colW)
+	}
+	var lines []string
+	starts := make([]int, 0, len(blocks))
+	n := 0
+	for _, seg := range m.conflict.Segments {
+		if seg.Block == nil {
+			for _, l := range collapseContext(seg.Lines) {
+				lines = append(lines, faint.Render("  "+l))
+			}
+			continue
+		}
+		b := seg.Block
+		starts = append(starts, len(lines))
+		title := fmt.Sprintf("conflict %d/%d", n+1, len(blocks))
+		if n == m.diffCursor && m.diffFocus {
+			title = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("63")).Render("▶ " + title + "  (o: ours, t: theirs)")
+		} else {
+			title = lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Render(title)
+		}
+		lines = append(lines, title)
+		ours, theirs := "ours", "theirs"
+		if l := b.OursLabel(); l != "" {
+			ours += " (" + l + ")"
+		}
+		if l := b.TheirsLabel(); l != "" {
+			theirs += " (" + l + ")"
+		}
+		bold := lipgloss.NewStyle().Bold(true)
+		head := []string{ours, "base", theirs}
+		for i := range head {
+			head[i] = padExact(ansi.Truncate(bold.Render(head[i]), colW, "…"), colW)
+		}
+		lines = append(lines, strings.Join(head, mid))
+		base := b.Base
+		if !b.BaseKnown {
+			base = []string{"(base unavailable)"}
+		}
+		rows := max(len(b.Ours), len(base), len(b.Theirs))
+		for i := 0; i < rows; i++ {
+			lines = append(lines, cell(b.Ours, i, oursStyle)+mid+cell(base, i, faint)+mid+cell(b.Theirs, i, theirsStyle))
+		}
+		n++
+	}
+	return lines, starts
+}
+
+// collapseContext keeps a few lines around each conflict block.
+func collapseContext(lines []string) []string {
+	const keep = 3
+	out := make([]string, 0, 2*keep+1)
+	if len(lines) <= 2*keep+1 {
+		for _, l := range lines {
+			out = append(out, strings.TrimRight(l, "\r\n"))
+		}
+		return out
+	}
+	for _, l := range lines[:keep] {
+		out = append(out, strings.TrimRight(l, "\r\n"))
+	}
+	out = append(out, fmt.Sprintf("⋯ %d unchanged lines ⋯", len(lines)-2*keep))
+	for _, l := range lines[len(lines)-keep:] {
+		out = append(out, strings.TrimRight(l, "\r\n"))
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package tui
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/interpretive-systems/diffium/internal/diffview"
+	"github.com/interpretive-systems/diffium/internal/nvim"
+)
+
+// --- Open the selected file in $VISUAL / $EDITOR ---
+
+// openInEditor suspends the TUI and edits the selected file at the line
+// under the diff cursor. The file list and diff are reloaded on return.
+// Inside a Neovim terminal the file is opened in that Neovim instead.
+func (m *model) openInEditor() tea.Cmd {
+	if len(m.files) == 0 {
+		return nil
+	}
+	f := m.files[m.selected]
+	path := filepath.Join(m.repoRoot, f.Path)
+	if _, err := os.Stat(path); err != nil {
+		m.status = f.Path + " is not in the working tree"
+		return nil
+	}
+	line := m.editLine()
+	if addr := nvim.Addr(); addr != "" {
+		return func() tea.Msg {
+			err := nvim.OpenFile(addr, path, line)
+			return patchResultMsg{done: "opened " + f.Path + " in nvim", err: err}
+		}
+	}
+	cmd, err := editorCommand(path, line)
+	if err != nil {
+		m.status = err.Error()
+		return nil
+	}
+	cmd.Dir = m.repoRoot
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return patchResultMsg{done: "edited " + f.Path, err: err}
+	})
+}
+
+// editLine is the new-side line of the diff cursor (see
+// diffview.NewPosition), or the first line of the conflict block under the
+// cursor. Without diff focus it is the line of the first change in view, or
+// of the first change in the file. It is 0 when unknown, such as while the
+// diff of the previously selected file is still shown.
+func (m model) editLine() int {
+	if m.conflictView() {
+		return conflictBlockLine(m.conflict, m.diffCursor)
+	}
+	if len(m.files) == 0 || m.rowsPath != m.files[m.selected].Path {
+		return 0
+	}
+	row, ok := m.diffCursor, m.diffFocus
+	if !ok {
+		row, ok = m.firstVisibleChange()
+	}
+	for i := 0; !ok && i < len(m.rows); i++ {
+		row, ok = i, m.rows[i].IsChange()
+	}
+	if !ok {
+		return 0
+	}
+	line, _, _ := diffview.NewPosition(m.rows, row)
+	return line
+}
+
+// conflictBlockLine returns the line of the <<<<<<< marker of block n.
+func conflictBlockLine(f *diffview.ConflictFile, n int) int {
+	line := 1
+	for _, s := range f.Segments {
+		if s.Block == nil {
+			line += len(s.Lines)
+			continue
+		}
+		if n == 0 {
+			return line
+		}
+		n--
+		b := s.Block
+		line += 3 + len(b.Ours) + len(b.Theirs)
+		if b.BaseMarker != "" {
+			line += 1 + len(b.Base)
+		}
+	}
+	return 0
+}
+
+// editorCommand builds the command for $VISUAL, $EDITOR or vi. Most editors
+// take "+line file"; a few GUI editors want "file:line" instead.
+func editorCommand(path string, line int) (*exec.Cmd, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	args := strings.Fields(editor)
+	name := filepath.Base(args[0])
+	switch {
+	case line <= 0:
+		args = append(args, path)
+	case name == "code" || name == "code-insiders" || name == "codium" || name == "cursor":
+		args = append(args, "--goto", path+":"+strconv.Itoa(line))
+	case name == "subl" || name == "zed" || name == "hx":
+		args = append(args, path+":"+strconv.Itoa(line))
+	default:
+		args = append(args, "+"+strconv.Itoa(line), path)
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, err
+	}
+	return exec.Command(args[0], args[1:]...), nil
+}
@@ -0,0 +1,165 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/interpretive-systems/diffium/internal/diffview"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+)
+
+// --- Review findings: marked diff rows exported as file:line:col: message ---
+
+// findingsFile is where F writes the findings, inside the git directory.
+const findingsFile = "diffium/findings.txt"
+
+// finding is a marked row. Line and Col are positions in the new file.
+type finding struct {
+	Path    string
+	Line    int
+	Col     int
+	Message string
+	text    string // the row's line, to find the row again after a refresh
+}
+
+type findingsExportMsg struct {
+	path string
+	n    int
+	err  error
+}
+
+// rowText is the line a finding on row r refers to: the new side, or the
+// old one for a deletion.
+func rowText(r diffview.Row) string {
+	if r.Kind == diffview.RowDel {
+		return r.Left
+	}
+	return r.Right
+}
+
+// findingAt returns the index of the finding on row i, or -1.
+func (m model) findingAt(i int) int {
+	if i < 0 || i >= len(m.rows) {
+		return -1
+	}
+	line, _, ok := diffview.NewPosition(m.rows, i)
+	if !ok {
+		return -1
+	}
+	text := rowText(m.rows[i])
+	for n, f := range m.findings {
+		if f.Path == m.rowsPath && f.Line == line && f.text == text {
+			return n
+		}
+	}
+	return -1
+}
+
+// toggleFinding removes the finding on the cursor row, or starts a new one
+// and prompts for its message.
+func (m *model) toggleFinding() {
+	if n := m.findingAt(m.diffCursor); n >= 0 {
+		m.findings = append(m.findings[:n], m.findings[n+1:]...)
+		m.status = fmt.Sprintf("unmarked (%d findings)", len(m.findings))
+		return
+	}
+	line, col, ok := diffview.NewPosition(m.rows, m.diffCursor)
+	if !ok || m.rowsPath == "" {
+		m.status = "no line under cursor"
+		return
+	}
+	m.fdPending = finding{Path: m.rowsPath, Line: line, Col: col, text: rowText(m.rows[m.diffCursor])}
+	ti := textinput.New()
+	ti.Placeholder = "What needs fixing? (empty: quote the line)"
+	ti.Prompt = "> "
+	ti.CharLimit = 0
+	ti.Focus()
+	m.fdInput = ti
+	m.fdActive = true
+}
+
+func (m model) handleFindingKeys(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.String() {
+	case "esc":
+		m.fdActive = false
+		return m, m.recalcViewport()
+	case "ctrl+c":
+		return m, tea.Quit
+	case "enter":
+		f := m.fdPending
+		f.Message = strings.TrimSpace(m.fdInput.Value())
+		if f.Message == "" {
+			f.Message = strings.TrimSpace(f.text)
+		}
+		m.findings = append(m.findings, f)
+		m.fdActive = false
+		m.status = fmt.Sprintf("marked %s:%d (%d findings, F: export)", f.Path, f.Line, len(m.findings))
+		return m, m.recalcViewport()
+	}
+	var cmd tea.Cmd
+	m.fdInput, cmd = m.fdInput.Update(key)
+	return m, cmd
+}
+
+func (m model) findingOverlayLines(width int) []string {
+	if !m.fdActive || width <= 0 {
+		return nil
+	}
+	f := m.fdPending
+	title := lipgloss.NewStyle().Bold(true).Render(fmt.Sprintf("Finding at %s:%d:%d", f.Path, f.Line, f.Col))
+	return []string{
+		m.theme.DividerText(strings.Repeat("─", width)),
+		padToWidth(title, width),
+		padToWidth(m.fdInput.View(), width),
+		padToWidth(lipgloss.NewStyle().Faint(true).Render("enter: mark, esc: cancel"), width),
+	}
+}
+
+// exportFindings writes the findings to the git directory.
+func (m model) exportFindings() tea.Cmd {
+	repoRoot := m.repoRoot
+	fs := append([]finding(nil), m.findings...)
+	return func() tea.Msg {
+		path, err := writeFindings(repoRoot, fs)
+		return findingsExportMsg{path: path, n: len(fs), err: err}
+	}
+}
+
+func writeFindings(repoRoot string, fs []finding) (string, error) {
+	path, err := gitx.GitPath(repoRoot, findingsFile)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, []byte(formatFindings(fs)), 0o644)
+}
+
+// formatFindings renders findings one per line as "path:line:col: message"
+// (Vim's %f:%l:%c: %m), sorted by position. Paths are relative to the
+// repository root.
+func formatFindings(fs []finding) string {
+	fs = append([]finding(nil), fs...)
+	sort.SliceStable(fs, func(i, j int) bool {
+		a, b := fs[i], fs[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	var b strings.Builder
+	for _, f := range fs {
+		fmt.Fprintf(&b, "%s:%d:%d: %s\n", f.Path, f.Line, f.Col, f.Message)
+	}
+	return b.String()
+}
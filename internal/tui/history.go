@@ -0,0 +1,140 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+)
+
+// --- History browser: pick a commit, view it in the main panes ---
+
+const (
+	logLimit   = 500 // commits loaded into the panel
+	logVisible = 10  // rows shown at once
+)
+
+type logMsg struct {
+	commits []gitx.LogEntry
+	err     error
+}
+
+func loadLog(repoRoot string) tea.Cmd {
+	return func() tea.Msg {
+		commits, err := gitx.Log(repoRoot, logLimit)
+		return logMsg{commits: commits, err: err}
+	}
+}
+
+func (m *model) openLogPanel() {
+	m.showLog = true
+	m.lgErr = ""
+	if !m.lgBrowsing {
+		m.lgIndex = 0
+		m.lgOffset = 0
+	}
+}
+
+func (m model) logOverlayLines(width int) []string {
+	if !m.showLog {
+		return nil
+	}
+	lines := make([]string, 0, logVisible+4)
+	lines = append(lines, strings.Repeat("─", width))
+	title := lipgloss.NewStyle().Bold(true).Render("History — Select (enter: view commit, esc: close)")
+	lines = append(lines, title)
+	if m.lgErr != "" {
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("Error: ")+m.lgErr)
+		return lines
+	}
+	if m.lgCommits == nil {
+		lines = append(lines, lipgloss.NewStyle().Faint(true).Render("Loading history…"))
+		return lines
+	}
+	if len(m.lgCommits) == 0 {
+		lines = append(lines, lipgloss.NewStyle().Faint(true).Render("No commits yet"))
+		return lines
+	}
+	hash := lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
+	faint := lipgloss.NewStyle().Faint(true)
+	end := m.lgOffset + logVisible
+	if end > len(m.lgCommits) {
+		end = len(m.lgCommits)
+	}
+	for i := m.lgOffset; i < end; i++ {
+		c := m.lgCommits[i]
+		cur := "  "
+		if i == m.lgIndex {
+			cur = "> "
+		}
+		lines = append(lines, fmt.Sprintf("%s%s %s %s  %s", cur, hash.Render(c.Short), faint.Render(c.Date.Format("2006-01-02 15:04")), faint.Render(c.Author), c.Subject))
+	}
+	lines = append(lines, faint.Render(fmt.Sprintf("%d/%d", m.lgIndex+1, len(m.lgCommits))))
+	return lines
+}
+
+func (m model) handleLogKeys(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.String() {
+	case "esc", "q":
+		m.showLog = false
+		return m, m.recalcViewport()
+	case "j", "down":
+		if m.lgIndex < len(m.lgCommits)-1 {
+			m.lgIndex++
+		}
+	case "k", "up":
+		if m.lgIndex > 0 {
+			m.lgIndex--
+		}
+	case "g":
+		m.lgIndex = 0
+	case "G":
+		m.lgIndex = len(m.lgCommits) - 1
+	case "enter":
+		if len(m.lgCommits) == 0 {
+			return m, nil
+		}
+		c := m.lgCommits[m.lgIndex]
+		if !m.lgBrowsing {
+			m.lgPrevRange = m.diffRange
+			m.lgBrowsing = true
+		}
+		m.showLog = false
+		m.status = c.Short + " " + c.Subject
+		return m, m.switchRange(c.Range())
+	}
+	if m.lgIndex < m.lgOffset {
+		m.lgOffset = m.lgIndex
+	} else if m.lgIndex >= m.lgOffset+logVisible {
+		m.lgOffset = m.lgIndex - logVisible + 1
+	}
+	return m, nil
+}
+
+// leaveHistory returns from a commit opened in the history browser to what
+// was shown before.
+func (m *model) leaveHistory() tea.Cmd {
+	m.lgBrowsing = false
+	m.status = ""
+	return m.switchRange(m.lgPrevRange)
+}
+
+// switchRange shows r (or the working tree for a zero Range) in the panes.
+func (m *model) switchRange(r gitx.Range) tea.Cmd {
+	m.selected = 0
+	m.leftOffset = 0
+	return m.previewRange(r)
+}
+
+// previewRange is switchRange keeping the selected file when r has it, for
+// panels that show what is under their cursor.
+func (m *model) previewRange(r gitx.Range) tea.Cmd {
+	m.diffRange = r
+	m.diffFocus = false
+	m.visualActive = false
+	m.rows = nil
+	m.rightVP.GotoTop()
+	return tea.Batch(m.reloadFiles(), m.recalcViewport())
+}
@@ -16,6 +16,9 @@ import (
 	"github.com/interpretive-systems/diffium/internal/diffview"
 	"github.com/interpretive-systems/diffium/internal/gitx"
 	"github.com/interpretive-systems/diffium/internal/prefs"
+	"github.com/interpretive-systems/diffium/internal/review"
+	"github.com/interpretive-systems/diffium/internal/syntax"
+	"github.com/interpretive-systems/diffium/internal/watch"
 )
 
 const (
@@ -29,6 +32,7 @@ const (
 
 type model struct {
 	repoRoot       string
+	watcher        *watch.Watcher // nil when polling
 	theme          Theme
 	files          []gitx.FileChange
 	selected       int
@@ -46,9 +50,56 @@ type model struct {
 	rightVP        viewport.Model
 	rightXOffset   int
 	wrapLines      bool
+	lineNumbers    bool
 
 	rightContent []string
 
+	// diff pane cursor (hunk/line staging)
+	diffFocus  bool
+	diffCursor int    // index into rows
+	rowStarts  []int  // first rightContent line of each row
+	rowsPath   string // path the current rows belong to
+	// syntax highlighting of the old and new file, per line (see syntax.go)
+	synPath        string
+	synOld, synNew [][]syntax.Span
+	// visual line selection in the diff pane
+	visualActive bool
+	visualAnchor int
+	// three-way view of a conflicted file; diffCursor indexes its blocks
+	conflict     *diffview.ConflictFile
+	conflictPath string
+
+	// commit range under review; zero when showing the working tree
+	diffRange gitx.Range
+	// latest snapshot against the working tree, in diffMode "snapshot";
+	// noSnapshot is set while there is none to compare with
+	snapRange  gitx.Range
+	noSnapshot bool
+
+	// history browser
+	showLog     bool
+	lgCommits   []gitx.LogEntry
+	lgIndex     int
+	lgOffset    int
+	lgErr       string
+	lgBrowsing  bool       // a commit from the log is shown in the panes
+	lgPrevRange gitx.Range // what to return to when leaving the commit
+
+	// checkpoint timeline (see timeline.go)
+	checkpoints     bool // record the working tree as it changes
+	lastCheckpoint  time.Time
+	noCheckpoints   bool // --no-checkpoints: not even before undoing a reset
+	checkpointing   bool // a checkpoint is being taken
+	checkpointAgain bool // and another one was asked for meanwhile
+	showTimeline    bool
+	tlList          []gitx.Snapshot // newest first
+	tlIndex         int
+	tlOffset        int
+	tlPin           string // id of the checkpoint pinned as the other side
+	tlErr           string
+	tlBrowsing      bool       // a checkpoint comparison is shown in the panes
+	tlPrevRange     gitx.Range // what to return to when leaving it
+
 	keyBuffer string
 	// commit wizard state
 	showCommit    bool
@@ -107,20 +158,71 @@ type model struct {
 	plDone    bool
 	plOutput  string
 
+	// stash wizard (see stash.go)
+	showStash   bool
+	stStep      int    // 0: list, 1: confirm (yellow), 2: confirm drop (red), 3: select files, 4: message
+	stVerb      string // "apply", "pop" or "drop" while confirming
+	stList      []gitx.StashEntry
+	stIndex     int
+	stOffset    int
+	stErr       string
+	stRunning   bool
+	stPrevRange gitx.Range // what the panes showed before the wizard
+	stFiles     []gitx.FileChange
+	stSelected  map[string]bool
+	stFileIndex int
+	stUntracked bool
+	stInput     textinput.Model
+
+	// discard hunk/selection wizard
+	showDiscard bool
+	dcStep      int // 0: confirm (yellow), 1: confirm (red)
+	dcPatch     string
+	dcWhat      string
+	dcPath      string
+	dcRunning   bool
+	dcErr       string
+
+	// undo confirmation (see undo.go)
+	showUndo  bool
+	udStep    int // 0: confirm (yellow), 1: confirm (red)
+	udOp      gitx.UndoOp
+	udLoaded  bool
+	udRunning bool
+	udErr     string
+
 	// search state
 	searchActive  bool
 	searchInput   textinput.Model
 	searchQuery   string
 	searchMatches []int
 	searchIndex   int
+
+	// files and hunks marked reviewed (see review.go); reviewed holds the
+	// fully reviewed state of each file with marks
+	review   *review.Store
+	reviewed map[string]bool
+
+	// review findings (see findings.go)
+	findings  []finding
+	fdActive  bool
+	fdInput   textinput.Model
+	fdPending finding
 }
 
 // messages
 type tickMsg struct{}
 
+// fsChangeMsg reports a settled burst of filesystem changes; ok is false
+// once the watcher has stopped.
+type fsChangeMsg struct{ ok bool }
+
 type filesMsg struct {
-	files []gitx.FileChange
-	err   error
+	files    []gitx.FileChange
+	snapshot gitx.Range // what the files compare in snapshot mode
+	// snapshot mode without a snapshot to compare with
+	noSnapshot bool
+	err        error
 }
 
 type diffMsg struct {
@@ -129,9 +231,31 @@ type diffMsg struct {
 	err  error
 }
 
+// Options configures the watch TUI.
+type Options struct {
+	// Poll refreshes every second instead of using filesystem events.
+	Poll bool
+	// Range, when set, reviews a commit range instead of the working tree.
+	Range gitx.Range
+	// NoCheckpoints turns off the automatic working tree checkpoints.
+	NoCheckpoints bool
+}
+
 // Run instantiates and runs the Bubble Tea program.
-func Run(repoRoot string) error {
-	m := model{repoRoot: repoRoot, sideBySide: true, diffMode: "head", theme: loadThemeFromRepo(repoRoot)}
+func Run(repoRoot string, opts Options) error {
+	m := model{repoRoot: repoRoot, sideBySide: true, diffMode: "head", diffRange: opts.Range, theme: LoadTheme(repoRoot)}
+	m.openReview()
+	// a range with a head commit never looks at the working tree
+	m.checkpoints = !opts.NoCheckpoints && opts.Range.Head == ""
+	m.noCheckpoints = opts.NoCheckpoints
+	m.checkpointing = m.checkpoints // Init takes the first one
+	if !opts.Poll {
+		// Fall back to polling when the platform has no watcher backend
+		if w, err := watch.New(repoRoot); err == nil {
+			m.watcher = w
+			defer w.Close()
+		}
+	}
 	p := tea.NewProgram(m, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		return err
@@ -140,15 +264,33 @@ func Run(repoRoot string) error {
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(loadFiles(m.repoRoot, m.diffMode), loadLastCommit(m.repoRoot), loadCurrentBranch(m.repoRoot), loadPrefs(m.repoRoot), tickOnce())
+	var cp tea.Cmd
+	if m.checkpoints {
+		cp = runCheckpoint(m.repoRoot)
+	}
+	return tea.Batch(m.reloadFiles(), loadLastCommit(m.repoRoot), loadCurrentBranch(m.repoRoot), loadPrefs(m.repoRoot), cp, m.nextRefresh())
+}
+
+// nextRefresh schedules the next automatic refresh: the next filesystem
+// event when watching, or a one-second tick when polling.
+func (m model) nextRefresh() tea.Cmd {
+	if m.watcher != nil {
+		return waitForChange(m.watcher)
+	}
+	return tickOnce()
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// the status reports the last action; the next one replaces it
+		m.status = ""
 		if m.searchActive {
 			return m.handleSearchKeys(msg)
 		}
+		if m.fdActive {
+			return m.handleFindingKeys(msg)
+		}
 		if m.showHelp {
 			switch msg.String() {
 			case "q":
@@ -176,6 +318,31 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.showPull {
 			return m.handlePullKeys(msg)
 		}
+		if m.showStash {
+			return m.handleStashKeys(msg)
+		}
+		if m.showDiscard {
+			return m.handleDiscardKeys(msg)
+		}
+		if m.showUndo {
+			return m.handleUndoKeys(msg)
+		}
+		if m.showLog {
+			return m.handleLogKeys(msg)
+		}
+		if m.showTimeline {
+			return m.handleTimelineKeys(msg)
+		}
+
+		if m.diffFocus && m.conflictView() {
+			if nm, cmd, ok := m.handleConflictKeys(msg); ok {
+				return nm, cmd
+			}
+		} else if m.diffFocus {
+			if nm, cmd, ok := m.handleDiffKeys(msg); ok {
+				return nm, cmd
+			}
+		}
 
 		key := msg.String()
 
@@ -197,6 +364,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, m.recalcViewport()
 		case "c":
 			// Open commit wizard
+			if m.inRange() {
+				m.status = "commit is unavailable while reviewing " + m.diffRange.Label
+				return m, nil
+			}
 			(&m).closeSearch()
 			m.openCommitWizard()
 			return m, m.recalcViewport()
@@ -210,6 +381,35 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "p":
 			m.openPullWizard()
 			return m, m.recalcViewport()
+		case "z":
+			(&m).closeSearch()
+			m.openStashWizard()
+			return m, tea.Batch(loadStashes(m.repoRoot), m.recalcViewport())
+		case "l":
+			m.openLogPanel()
+			return m, tea.Batch(loadLog(m.repoRoot), m.recalcViewport())
+		case "T":
+			(&m).openTimeline()
+			return m, tea.Batch(loadTimeline(m.repoRoot), m.recalcViewport())
+		case "e":
+			return m, m.openInEditor()
+		case "F":
+			return m, m.exportFindings()
+		case "x":
+			(&m).toggleReviewed(false)
+			return m, m.recalcViewport()
+		case "S":
+			return m, takeSnapshot(m.repoRoot)
+		case "U":
+			return m, (&m).openUndo()
+		case "esc":
+			if m.tlBrowsing {
+				return m, m.leaveTimeline()
+			}
+			if m.lgBrowsing {
+				return m, m.leaveHistory()
+			}
+			return m, nil
 		case "R":
 			// Open reset/clean wizard
 			m.openResetCleanWizard()
@@ -217,6 +417,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "/":
 			(&m).openSearch()
 			return m, m.recalcViewport()
+		case "tab":
+			(&m).focusDiff()
+			return m, m.recalcViewport()
 		case "<", "H":
 			if m.leftWidth == 0 {
 				m.leftWidth = m.width / 3
@@ -261,7 +464,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.rows = nil
 				// Reset scroll for new file
 				m.rightVP.GotoTop()
-				return m, tea.Batch(loadDiff(m.repoRoot, m.files[m.selected].Path, m.diffMode), m.recalcViewport())
+				return m, tea.Batch(loadCurrentDiff(m), m.recalcViewport())
 			}
 		case "k", "up":
 			if len(m.files) == 0 {
@@ -282,21 +485,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				m.rows = nil
 				m.rightVP.GotoTop()
-				return m, tea.Batch(loadDiff(m.repoRoot, m.files[m.selected].Path, m.diffMode), m.recalcViewport())
+				return m, tea.Batch(loadCurrentDiff(m), m.recalcViewport())
 			}
 		case "g":
 			if len(m.files) > 0 {
 				m.selected = 0
 				m.rows = nil
 				m.rightVP.GotoTop()
-				return m, tea.Batch(loadDiff(m.repoRoot, m.files[m.selected].Path, m.diffMode), m.recalcViewport())
+				return m, tea.Batch(loadCurrentDiff(m), m.recalcViewport())
 			}
 		case "G":
 			if len(m.files) > 0 {
 				m.selected = len(m.files) - 1
 				m.rows = nil
 				m.rightVP.GotoTop()
-				return m, tea.Batch(loadDiff(m.repoRoot, m.files[m.selected].Path, m.diffMode), m.recalcViewport())
+				return m, tea.Batch(loadCurrentDiff(m), m.recalcViewport())
 			}
 		case "[":
 			// Page up left pane
@@ -357,21 +560,32 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "N":
 			return m, (&m).advanceSearch(-1)
 		case "r":
-			return m, tea.Batch(loadFiles(m.repoRoot, m.diffMode), loadCurrentDiff(m))
+			return m, tea.Batch(m.reloadFiles(), loadCurrentDiff(m))
 		case "s":
 			m.sideBySide = !m.sideBySide
 			_ = prefs.SaveSideBySide(m.repoRoot, m.sideBySide)
 			return m, m.recalcViewport()
+		case "#":
+			m.lineNumbers = !m.lineNumbers
+			_ = prefs.SaveLineNumbers(m.repoRoot, m.lineNumbers)
+			return m, m.recalcViewport()
 		case "t":
-			if m.diffMode == "head" {
+			if m.inRange() {
+				m.status = "reviewing " + m.diffRange.Label + "; no staged view"
+				return m, nil
+			}
+			switch m.diffMode {
+			case "head":
 				m.diffMode = "staged"
-			} else {
+			case "staged":
+				m.diffMode = "snapshot"
+			default:
 				m.diffMode = "head"
 			}
 			m.rows = nil
 			m.selected = 0
 			m.rightVP.GotoTop()
-			return m, tea.Batch(loadFiles(m.repoRoot, m.diffMode), m.recalcViewport())
+			return m, tea.Batch(m.reloadFiles(), m.recalcViewport())
 		case "w":
 			// Toggle wrap in diff pane
 			m.wrapLines = !m.wrapLines
@@ -450,12 +664,25 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, m.recalcViewport()
 	case tickMsg:
 		// Periodic refresh
-		return m, tea.Batch(loadFiles(m.repoRoot, m.diffMode), loadCurrentBranch(m.repoRoot), tickOnce())
+		cp := (&m).checkpointDue()
+		return m, tea.Batch(m.reloadFiles(), loadCurrentBranch(m.repoRoot), cp, tickOnce())
+	case fsChangeMsg:
+		if !msg.ok {
+			// Watcher died (e.g. too many directories); keep going by polling
+			m.watcher = nil
+			m.status = "file watcher stopped; polling every second"
+			return m, tickOnce()
+		}
+		cp := (&m).checkpoint()
+		return m, tea.Batch(m.reloadFiles(), loadCurrentBranch(m.repoRoot), loadLastCommit(m.repoRoot), cp, waitForChange(m.watcher))
 	case filesMsg:
 		if msg.err != nil {
 			m.status = fmt.Sprintf("status error: %v", msg.err)
 			return m, nil
 		}
+		if m.diffMode == "snapshot" && (msg.snapshot.Base != "" || msg.noSnapshot) {
+			m.snapRange, m.noSnapshot = msg.snapshot, msg.noSnapshot
+		}
 		// Stable-sort files by path for deterministic UI
 		sort.Slice(msg.files, func(i, j int) bool { return msg.files[i].Path < msg.files[j].Path })
 
@@ -479,7 +706,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		// Load diff for selected if exists
 		if len(m.files) > 0 {
-			return m, tea.Batch(loadDiff(m.repoRoot, m.files[m.selected].Path, m.diffMode), m.recalcViewport())
+			return m, tea.Batch(loadCurrentDiff(m), m.recalcViewport())
 		}
 		m.rows = nil
 		return m, m.recalcViewport()
@@ -492,8 +719,58 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Only update if this diff is for the currently selected file
 		if len(m.files) > 0 && m.files[m.selected].Path == msg.path {
 			m.rows = msg.rows
+			if m.rowsPath != msg.path {
+				m.rowsPath = msg.path
+				m.diffCursor = 0
+				m.visualActive = false
+			}
+			(&m).clampDiffCursor()
+			(&m).refreshReviewed()
+		}
+		return m, m.recalcViewport()
+	case reviewMsg:
+		return m.reviewResult(msg)
+	case snapshotMsg:
+		return m.snapshotResult(msg)
+	case stashListMsg:
+		return m.stashListResult(msg)
+	case stashRangeMsg:
+		return m.stashRangeResult(msg)
+	case stashFilesMsg:
+		return m.stashFilesResult(msg)
+	case stashResultMsg:
+		return m.stashResult(msg)
+	case checkpointMsg:
+		return m.checkpointResult(msg)
+	case timelineMsg:
+		return m.timelineResult(msg)
+	case patchResultMsg:
+		return m.patchResult(msg)
+	case findingsExportMsg:
+		if msg.err != nil {
+			m.status = "export findings: " + msg.err.Error()
+		} else {
+			m.status = fmt.Sprintf("wrote %d findings to %s", msg.n, msg.path)
+		}
+		return m, nil
+	case conflictMsg:
+		return m.conflictResult(msg)
+	case syntaxMsg:
+		if len(m.files) > 0 && m.files[m.selected].Path == msg.path {
+			m.synPath, m.synOld, m.synNew = msg.path, msg.old, msg.new
 		}
 		return m, m.recalcViewport()
+	case discardResultMsg:
+		m.dcRunning = false
+		if msg.err != nil {
+			m.dcErr = strings.ReplaceAll(strings.TrimSpace(msg.err.Error()), "\n", " ")
+			return m, m.recalcViewport()
+		}
+		m.dcErr = ""
+		m.showDiscard = false
+		m.visualActive = false
+		m.status = "discarded " + m.dcWhat
+		return m, tea.Batch(m.reloadFiles(), loadCurrentDiff(m), m.recalcViewport())
 	case lastCommitMsg:
 		if msg.err == nil {
 			m.lastCommit = msg.summary
@@ -509,6 +786,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if msg.p.SideSet {
 				m.sideBySide = msg.p.SideBySide
 			}
+			if msg.p.LineNumSet {
+				m.lineNumbers = msg.p.LineNums
+			}
 			if msg.p.WrapSet {
 				m.wrapLines = msg.p.Wrap
 				if m.wrapLines {
@@ -548,7 +828,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.plDone = true
 		m.showPull = true
 		// Refresh repo state after pull
-		return m, tea.Batch(loadFiles(m.repoRoot, m.diffMode), loadLastCommit(m.repoRoot), loadCurrentBranch(m.repoRoot), m.recalcViewport())
+		return m, tea.Batch(m.reloadFiles(), loadLastCommit(m.repoRoot), loadCurrentBranch(m.repoRoot), m.recalcViewport())
+	case logMsg:
+		if msg.err != nil {
+			m.lgErr = msg.err.Error()
+			return m, m.recalcViewport()
+		}
+		m.lgCommits = msg.commits
+		if m.lgCommits == nil {
+			m.lgCommits = []gitx.LogEntry{}
+		}
+		if m.lgIndex >= len(m.lgCommits) {
+			m.lgIndex = 0
+			m.lgOffset = 0
+		}
+		return m, m.recalcViewport()
 	case branchListMsg:
 		if msg.err != nil {
 			m.brErr = msg.err.Error()
@@ -580,7 +874,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.brDone = true
 		m.showBranch = false
 		// refresh files after checkout
-		return m, tea.Batch(loadFiles(m.repoRoot, m.diffMode), loadLastCommit(m.repoRoot), loadCurrentBranch(m.repoRoot), m.recalcViewport())
+		return m, tea.Batch(m.reloadFiles(), loadLastCommit(m.repoRoot), loadCurrentBranch(m.repoRoot), m.recalcViewport())
 	case rcPreviewMsg:
 		m.rcPreviewErr = ""
 		if msg.err != nil {
@@ -595,12 +889,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.err != nil {
 			m.rcErr = msg.err.Error()
 			m.rcDone = false
-			return m, tea.Batch(loadFiles(m.repoRoot, m.diffMode), m.recalcViewport())
+			return m, tea.Batch(m.reloadFiles(), m.recalcViewport())
 		}
 		m.rcErr = ""
 		m.rcDone = true
 		m.showResetClean = false
-		return m, tea.Batch(loadFiles(m.repoRoot, m.diffMode), m.recalcViewport())
+		return m, tea.Batch(m.reloadFiles(), m.recalcViewport())
 	case commitProgressMsg:
 		m.committing = true
 		m.commitErr = ""
@@ -611,15 +905,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.commitErr = msg.err.Error()
 			m.commitDone = false
 			// refresh even on error (commit may have succeeded but push failed)
-			return m, tea.Batch(loadFiles(m.repoRoot, m.diffMode), loadLastCommit(m.repoRoot), m.recalcViewport())
+			return m, tea.Batch(m.reloadFiles(), loadLastCommit(m.repoRoot), m.recalcViewport())
 		} else {
 			m.commitErr = ""
 			m.commitDone = true
 			m.showCommit = false
 			// refresh changes and last commit
-			return m, tea.Batch(loadFiles(m.repoRoot, m.diffMode), loadLastCommit(m.repoRoot), m.recalcViewport())
+			return m, tea.Batch(m.reloadFiles(), loadLastCommit(m.repoRoot), m.recalcViewport())
 		}
-		return m, nil
 	case uncommitFilesMsg:
 		if msg.err != nil {
 			m.uncommitErr = msg.err.Error()
@@ -646,17 +939,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.ucEligible[p] = true
 		}
 		return m, m.recalcViewport()
+	case undoPreviewMsg:
+		return m.undoPreviewResult(msg)
+	case undoResultMsg:
+		return m.undoResult(msg)
 	case uncommitResultMsg:
 		m.uncommitting = false
 		if msg.err != nil {
 			m.uncommitErr = msg.err.Error()
 			m.uncommitDone = false
-			return m, tea.Batch(loadFiles(m.repoRoot, m.diffMode), loadLastCommit(m.repoRoot), m.recalcViewport())
+			return m, tea.Batch(m.reloadFiles(), loadLastCommit(m.repoRoot), m.recalcViewport())
 		}
 		m.uncommitErr = ""
 		m.uncommitDone = true
 		m.showUncommit = false
-		return m, tea.Batch(loadFiles(m.repoRoot, m.diffMode), loadLastCommit(m.repoRoot), m.recalcViewport())
+		return m, tea.Batch(m.reloadFiles(), loadLastCommit(m.repoRoot), m.recalcViewport())
 	}
 	return m, nil
 }
@@ -722,9 +1019,27 @@ func (m model) View() string {
 	if m.showPull {
 		overlay = append(overlay, m.pullOverlayLines(m.width)...)
 	}
+	if m.showStash {
+		overlay = append(overlay, m.stashOverlayLines(m.width)...)
+	}
+	if m.showDiscard {
+		overlay = append(overlay, m.discardOverlayLines(m.width)...)
+	}
+	if m.showUndo {
+		overlay = append(overlay, m.undoOverlayLines(m.width)...)
+	}
+	if m.showLog {
+		overlay = append(overlay, m.logOverlayLines(m.width)...)
+	}
+	if m.showTimeline {
+		overlay = append(overlay, m.timelineOverlayLines(m.width)...)
+	}
 	if m.searchActive {
 		overlay = append(overlay, m.searchOverlayLines(m.width)...)
 	}
+	if m.fdActive {
+		overlay = append(overlay, m.findingOverlayLines(m.width)...)
+	}
 	overlayH := len(overlay)
 
 	contentHeight := m.height - 4 - overlayH // top + top rule + bottom rule + bottom bar
@@ -786,6 +1101,10 @@ func (m model) View() string {
 func (m model) leftBodyLines(max int) []string {
 	lines := make([]string, 0, max)
 	if len(m.files) == 0 {
+		if m.diffMode == "snapshot" && m.noSnapshot {
+			lines = append(lines, "No snapshot yet — press S")
+			return lines
+		}
 		lines = append(lines, "No changes detected")
 		return lines
 	}
@@ -806,8 +1125,14 @@ func (m model) leftBodyLines(max int) []string {
 		if i == m.selected {
 			marker = "> "
 		}
-		status := fileStatusLabel(f)
-		line := fmt.Sprintf("%s%s %s", marker, status, f.Path)
+		status := StatusLabel(f)
+		line := fmt.Sprintf("%s%s %s", marker, status, DisplayPath(f))
+		if f.Conflicted {
+			line = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(line)
+		}
+		if m.reviewed[f.Path] {
+			line += " " + lipgloss.NewStyle().Foreground(lipgloss.Color("34")).Render("✓")
+		}
 		lines = append(lines, line)
 	}
 	return lines
@@ -875,9 +1200,9 @@ func (m model) rightBodyLines(max, width int) []string {
 
 func (m model) topRightTitle() string {
 	if len(m.files) == 0 {
-		return fmt.Sprintf("[%s]", strings.ToUpper(m.diffMode))
+		return fmt.Sprintf("[%s]", m.modeLabel())
 	}
-	header := fmt.Sprintf("%s (%s) [%s]", m.files[m.selected].Path, fileStatusLabel(m.files[m.selected]), strings.ToUpper(m.diffMode))
+	header := fmt.Sprintf("%s (%s) [%s]", DisplayPath(m.files[m.selected]), StatusLabel(m.files[m.selected]), m.modeLabel())
 	return header
 }
 
@@ -889,6 +1214,17 @@ func (m model) bottomBar() string {
 	if m.lastCommit != "" {
 		leftText += "  |  last: " + m.lastCommit
 	}
+	if m.visualActive {
+		leftText += "  |  -- VISUAL --"
+	}
+	if m.tlBrowsing {
+		leftText += "  |  esc: back, T: timeline"
+	} else if m.lgBrowsing {
+		leftText += "  |  esc: back, l: history"
+	}
+	if m.status != "" {
+		leftText += "  |  " + m.status
+	}
 	leftStyled := lipgloss.NewStyle().Faint(true).Render(leftText)
 	right := lipgloss.NewStyle().Faint(true).Render("refreshed: " + m.lastRefresh.Format("15:04:05"))
 	w := m.width
@@ -908,26 +1244,6 @@ func (m model) bottomBar() string {
 	return leftRendered + " " + right
 }
 
-func fileStatusLabel(f gitx.FileChange) string {
-	var tags []string
-	if f.Deleted {
-		tags = append(tags, "D")
-	}
-	if f.Untracked {
-		tags = append(tags, "U")
-	}
-	if f.Staged {
-		tags = append(tags, "S")
-	}
-	if f.Unstaged {
-		tags = append(tags, "M")
-	}
-	if len(tags) == 0 {
-		return "-"
-	}
-	return strings.Join(tags, "")
-}
-
 func loadFiles(repoRoot, diffMode string) tea.Cmd {
 	return func() tea.Msg {
 		allFiles, err := gitx.ChangedFiles(repoRoot)
@@ -953,15 +1269,13 @@ func loadFiles(repoRoot, diffMode string) tea.Cmd {
 	}
 }
 
-func loadDiff(repoRoot, path, diffMode string) tea.Cmd {
+func loadDiff(repoRoot string, f gitx.FileChange, diffMode string) tea.Cmd {
+	path := f.Path
+	if f.Conflicted && diffMode != "staged" {
+		return loadConflict(repoRoot, path)
+	}
 	return func() tea.Msg {
-		var d string
-		var err error
-		if diffMode == "staged" {
-			d, err = gitx.DiffStaged(repoRoot, path)
-		} else {
-			d, err = gitx.DiffHEAD(repoRoot, path)
-		}
+		d, err := gitx.DiffFile(repoRoot, f, diffMode == "staged")
 		if err != nil {
 			return diffMsg{path: path, err: err}
 		}
@@ -974,13 +1288,84 @@ func loadCurrentDiff(m model) tea.Cmd {
 	if len(m.files) == 0 {
 		return nil
 	}
-	return loadDiff(m.repoRoot, m.files[m.selected].Path, m.diffMode)
+	if m.inRange() {
+		return tea.Batch(loadRangeDiff(m.repoRoot, m.diffRange, m.files[m.selected]), m.loadSyntax())
+	}
+	if m.diffMode == "snapshot" {
+		return tea.Batch(loadRangeDiff(m.repoRoot, m.snapRange, m.files[m.selected]), m.loadSyntax())
+	}
+	return tea.Batch(loadDiff(m.repoRoot, m.files[m.selected], m.diffMode), m.loadSyntax())
+}
+
+// reloadFiles lists the files for the current diff source and refreshes
+// their review marks.
+func (m model) reloadFiles() tea.Cmd {
+	if m.inRange() {
+		return tea.Batch(loadRangeFiles(m.repoRoot, m.diffRange), m.loadReview())
+	}
+	if m.diffMode == "snapshot" {
+		return loadSnapshotFiles(m.repoRoot)
+	}
+	return tea.Batch(loadFiles(m.repoRoot, m.diffMode), m.loadReview())
+}
+
+// readOnly names what the diff pane shows when it cannot be staged from: a
+// commit range or the changes since the snapshot. It is "" otherwise.
+func (m model) readOnly() string {
+	switch {
+	case m.inRange():
+		return m.diffRange.Label
+	case m.diffMode == "snapshot":
+		return "changes since the snapshot"
+	}
+	return ""
+}
+
+// inRange reports whether a commit range is being reviewed instead of the
+// working tree and index.
+func (m model) inRange() bool {
+	return m.diffRange.Base != ""
+}
+
+// modeLabel names the diff source for the title bar.
+func (m model) modeLabel() string {
+	if m.inRange() {
+		return m.diffRange.Label
+	}
+	if m.diffMode == "snapshot" && m.snapRange.Label != "" {
+		return m.snapRange.Label
+	}
+	return strings.ToUpper(m.diffMode)
+}
+
+func loadRangeFiles(repoRoot string, r gitx.Range) tea.Cmd {
+	return func() tea.Msg {
+		files, err := gitx.RangeFiles(repoRoot, r)
+		return filesMsg{files: files, err: err}
+	}
+}
+
+func loadRangeDiff(repoRoot string, r gitx.Range, f gitx.FileChange) tea.Cmd {
+	return func() tea.Msg {
+		d, err := gitx.DiffRange(repoRoot, r, f)
+		if err != nil {
+			return diffMsg{path: f.Path, err: err}
+		}
+		return diffMsg{path: f.Path, rows: diffview.BuildRowsFromUnified(d)}
+	}
 }
 
 func tickOnce() tea.Cmd {
 	return tea.Tick(time.Second, func(time.Time) tea.Msg { return tickMsg{} })
 }
 
+func waitForChange(w *watch.Watcher) tea.Cmd {
+	return func() tea.Msg {
+		_, ok := <-w.Events
+		return fsChangeMsg{ok: ok}
+	}
+}
+
 func padToWidth(s string, w int) string {
 	width := lipgloss.Width(s)
 	if width == w {
@@ -1004,7 +1389,14 @@ func (m model) viewHelp() string {
 		"[/]            Page left file list",
 		"{/}            Horizontal scroll (diff)",
 		"b              Switch branch (open wizard)",
+		"z              Stash wizard",
 		"s              Toggle side-by-side / inline",
+		"l              History: browse commits, enter shows one (esc returns)",
+		"U              Undo last reset/clean, uncommit or checkout (confirms twice)",
+		"T              Timeline of automatic checkpoints (esc returns)",
+		"e              Edit file at the cursor line in $VISUAL/$EDITOR",
+		"m / F          Mark a finding (diff focus) / export findings",
+		"x              Mark file / hunk (diff focus) reviewed",
 		"r              Refresh now",
 		"g / G          Top / Bottom",
 		"h or Esc       Close help",
@@ -1064,9 +1456,27 @@ func (m *model) recalcViewport() tea.Cmd {
 	if m.showPull {
 		overlayH += len(m.pullOverlayLines(m.width))
 	}
+	if m.showStash {
+		overlayH += len(m.stashOverlayLines(m.width))
+	}
+	if m.showDiscard {
+		overlayH += len(m.discardOverlayLines(m.width))
+	}
+	if m.showUndo {
+		overlayH += len(m.undoOverlayLines(m.width))
+	}
+	if m.showLog {
+		overlayH += len(m.logOverlayLines(m.width))
+	}
+	if m.showTimeline {
+		overlayH += len(m.timelineOverlayLines(m.width))
+	}
 	if m.searchActive {
 		overlayH += len(m.searchOverlayLines(m.width))
 	}
+	if m.fdActive {
+		overlayH += len(m.findingOverlayLines(m.width))
+	}
 	contentHeight := m.height - 4 - overlayH
 	if contentHeight < 1 {
 		contentHeight = 1
@@ -1104,7 +1514,7 @@ func (m *model) recalcViewport() tea.Cmd {
 	m.rightVP.Width = rightW
 	m.rightVP.Height = contentHeight
 	// Build content
-	m.rightContent = m.rightBodyLinesAll(rightW)
+	m.rightContent, m.rowStarts = m.rightBodyLinesAll(rightW)
 
 	// Update search matches + highlight state
 	if m.searchQuery == "" {
@@ -1134,12 +1544,28 @@ func (m model) helpOverlayLines(width int) []string {
 		"[/]            Page left file list",
 		"b              Switch branch (open wizard)",
 		"p              Pull (open wizard)",
+		"z              Stash: list (diff in panes), new, apply, pop, drop",
 		"u              Uncommit (open wizard)",
 		"R              Reset/Clean (open wizard)",
 		"c              Commit & push (open wizard)",
 		"s              Toggle side-by-side / inline",
-		"t              Toggle HEAD / staged diffs",
+		"t              Cycle HEAD / staged / since-snapshot diffs",
+		"S              Take a snapshot of the working tree (see t)",
 		"w              Toggle line wrap (diff)",
+		"#              Toggle line numbers (diff)",
+		"tab            Focus diff pane (j/k: move cursor, [/]: hunks)",
+		"space          Stage hunk (HEAD) / unstage hunk (staged), diff focus",
+		"v              Select lines (diff focus); space stages/unstages them",
+		"d              Discard hunk or selected lines from working tree (diff focus)",
+		"m              Mark the cursor line as a finding, or unmark it (diff focus)",
+		"x              Mark file (or hunk, diff focus) reviewed; again to unmark",
+		"F              Export findings as file:line:col: message",
+		"o / t          Take ours / theirs for a conflict block (conflict, diff focus)",
+		"a              Mark conflicted file resolved, git add (conflict, diff focus)",
+		"l              History: browse commits, enter shows one (esc returns)",
+		"U              Undo the last reset/clean, uncommit or checkout (confirms twice)",
+		"T              Timeline: scrub checkpoints, diff two, restore a file",
+		"e              Edit file at the cursor line in $VISUAL/$EDITOR",
 		"r              Refresh now",
 		"g / G          Top / Bottom",
 		"q              Quit",
@@ -1177,8 +1603,8 @@ func (m model) commitOverlayLines(width int) []string {
 			if m.cwSelected[f.Path] {
 				mark = "[x]"
 			}
-			status := fileStatusLabel(f)
-			lines = append(lines, fmt.Sprintf("%s%s %s %s", cur, mark, status, f.Path))
+			status := StatusLabel(f)
+			lines = append(lines, fmt.Sprintf("%s%s %s %s", cur, mark, status, DisplayPath(f)))
 		}
 	case 1:
 		mode := "action"
@@ -1559,9 +1985,17 @@ func (m model) handleBranchKeys(key tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 func runCheckout(repoRoot, branch string) tea.Cmd {
 	return func() tea.Msg {
+		op, err := gitx.SaveCheckoutUndo(repoRoot, branch)
+		if err != nil {
+			return branchResultMsg{err: fmt.Errorf("save undo state: %w", err)}
+		}
 		if err := gitx.Checkout(repoRoot, branch); err != nil {
+			gitx.DiscardUndo(repoRoot, op)
 			return branchResultMsg{err: err}
 		}
+		if err := gitx.RecordUndo(repoRoot, op); err != nil {
+			return branchResultMsg{err: fmt.Errorf("record undo state: %w", err)}
+		}
 		return branchResultMsg{err: nil}
 	}
 }
@@ -1684,6 +2118,7 @@ func (m model) resetCleanOverlayLines(width int) []string {
 	case 3: // final (red) confirmation
 		title := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("196")).Render("FINAL CONFIRMATION — Destructive action (y/enter: execute, b: back, esc: cancel)")
 		lines = append(lines, title)
+		lines = append(lines, lipgloss.NewStyle().Faint(true).Render("The current state is saved first; U undoes this afterwards."))
 		if m.rcRunning {
 			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Render("Running…"))
 		}
@@ -1805,9 +2240,17 @@ func loadRCPreview(repoRoot string, includeIgnored bool) tea.Cmd {
 
 func runResetClean(repoRoot string, doReset, doClean bool, includeIgnored bool) tea.Cmd {
 	return func() tea.Msg {
+		op, err := gitx.SaveResetUndo(repoRoot, doReset, doClean, includeIgnored)
+		if err != nil {
+			return rcResultMsg{err: fmt.Errorf("save undo state: %w", err)}
+		}
 		if err := gitx.ResetAndClean(repoRoot, doReset, doClean, includeIgnored); err != nil {
+			gitx.DiscardUndo(repoRoot, op)
 			return rcResultMsg{err: err}
 		}
+		if err := gitx.RecordUndo(repoRoot, op); err != nil {
+			return rcResultMsg{err: fmt.Errorf("record undo state: %w", err)}
+		}
 		return rcResultMsg{err: nil}
 	}
 }
@@ -1874,8 +2317,8 @@ func (m model) uncommitOverlayLines(width int) []string {
 			if m.ucSelected[f.Path] {
 				mark = "[x]"
 			}
-			status := fileStatusLabel(f)
-			lines = append(lines, fmt.Sprintf("%s%s %s %s", cur, mark, status, f.Path))
+			status := StatusLabel(f)
+			lines = append(lines, fmt.Sprintf("%s%s %s %s", cur, mark, status, DisplayPath(f)))
 		}
 	case 1:
 		title := lipgloss.NewStyle().Bold(true).Render("Uncommit — Confirm (y/enter: uncommit, b: back, esc: cancel)")
@@ -2012,140 +2455,62 @@ func runUncommit(repoRoot string, paths []string) tea.Cmd {
 		if len(toUncommit) == 0 {
 			return uncommitResultMsg{err: fmt.Errorf("no selected files are in the last commit")}
 		}
+		op, err := gitx.SaveUncommitUndo(repoRoot, toUncommit)
+		if err != nil {
+			return uncommitResultMsg{err: fmt.Errorf("save undo state: %w", err)}
+		}
 		if err := gitx.UncommitFiles(repoRoot, toUncommit); err != nil {
+			gitx.DiscardUndo(repoRoot, op)
 			return uncommitResultMsg{err: err}
 		}
+		if err := gitx.RecordUndo(repoRoot, op); err != nil {
+			return uncommitResultMsg{err: fmt.Errorf("record undo state: %w", err)}
+		}
 		return uncommitResultMsg{err: nil}
 	}
 }
-func (m model) rightBodyLinesAll(width int) []string {
-	lines := make([]string, 0, 1024)
+
+// rightBodyLinesAll renders the full diff pane content. It also returns, for
+// each row in m.rows, the index of its first rendered line.
+func (m model) rightBodyLinesAll(width int) ([]string, []int) {
 	if len(m.files) == 0 {
-		return lines
+		return nil, nil
 	}
-	if m.files[m.selected].Binary {
-		lines = append(lines, lipgloss.NewStyle().Faint(true).Render("(Binary file; no text diff)"))
-		return lines
+	if m.conflictView() {
+		return m.conflictLines(width)
+	}
+	f := m.files[m.selected]
+	if f.Binary {
+		return RenderDiff(m.theme, f, nil, width, RenderOptions{})
 	}
 	if m.rows == nil {
-		lines = append(lines, "Loading diff…")
-		return lines
+		return []string{"Loading diff…"}, nil
 	}
-	if m.sideBySide {
-		colsW := (width - 1) / 2
-		if colsW < 10 {
-			colsW = 10
-		}
-		mid := m.theme.DividerText("│")
-		for _, r := range m.rows {
-			switch r.Kind {
-			case diffview.RowHunk:
-				// subtle separator fills full width
-				lines = append(lines, lipgloss.NewStyle().Faint(true).Render(strings.Repeat("·", width)))
-			case diffview.RowMeta:
-				// skip
-			default:
-				if m.wrapLines {
-					lLines := m.renderSideCellWrap(r, "left", colsW)
-					rLines := m.renderSideCellWrap(r, "right", colsW)
-					n := len(lLines)
-					if len(rLines) > n {
-						n = len(rLines)
-					}
-					for i := 0; i < n; i++ {
-						var l, rr string
-						if i < len(lLines) {
-							l = lLines[i]
-						} else {
-							l = strings.Repeat(" ", colsW)
-						}
-						if i < len(rLines) {
-							rr = rLines[i]
-						} else {
-							rr = strings.Repeat(" ", colsW)
-						}
-						lines = append(lines, l+mid+rr)
-					}
-				} else {
-					l := m.renderSideCell(r, "left", colsW)
-					rr := m.renderSideCell(r, "right", colsW)
-					l = padExact(l, colsW)
-					rr = padExact(rr, colsW)
-					lines = append(lines, l+mid+rr)
-				}
-			}
-		}
-	} else {
-		for _, r := range m.rows {
-			switch r.Kind {
-			case diffview.RowHunk:
-				lines = append(lines, lipgloss.NewStyle().Faint(true).Render(strings.Repeat("·", width)))
-			case diffview.RowContext:
-				base := "  " + r.Left
-				if m.wrapLines {
-					wrapped := ansi.Hardwrap(base, width, false)
-					lines = append(lines, strings.Split(wrapped, "\n")...)
-				} else {
-					line := base
-					if m.rightXOffset > 0 {
-						line = sliceANSI(line, m.rightXOffset, width)
-						line = padExact(line, width)
-					}
-					lines = append(lines, line)
-				}
-			case diffview.RowAdd:
-				base := m.theme.AddText("+ " + r.Right)
-				if m.wrapLines {
-					wrapped := ansi.Hardwrap(base, width, false)
-					lines = append(lines, strings.Split(wrapped, "\n")...)
-				} else {
-					line := base
-					if m.rightXOffset > 0 {
-						line = sliceANSI(line, m.rightXOffset, width)
-						line = padExact(line, width)
-					}
-					lines = append(lines, line)
-				}
-			case diffview.RowDel:
-				base := m.theme.DelText("- " + r.Left)
-				if m.wrapLines {
-					wrapped := ansi.Hardwrap(base, width, false)
-					lines = append(lines, strings.Split(wrapped, "\n")...)
-				} else {
-					line := base
-					if m.rightXOffset > 0 {
-						line = sliceANSI(line, m.rightXOffset, width)
-						line = padExact(line, width)
-					}
-					lines = append(lines, line)
-				}
-			case diffview.RowReplace:
-				base1 := m.theme.DelText("- " + r.Left)
-				base2 := m.theme.AddText("+ " + r.Right)
-				if m.wrapLines {
-					wrapped1 := strings.Split(ansi.Hardwrap(base1, width, false), "\n")
-					wrapped2 := strings.Split(ansi.Hardwrap(base2, width, false), "\n")
-					lines = append(lines, wrapped1...)
-					lines = append(lines, wrapped2...)
-				} else {
-					line1 := base1
-					if m.rightXOffset > 0 {
-						line1 = sliceANSI(line1, m.rightXOffset, width)
-						line1 = padExact(line1, width)
-					}
-					lines = append(lines, line1)
-					line2 := base2
-					if m.rightXOffset > 0 {
-						line2 = sliceANSI(line2, m.rightXOffset, width)
-						line2 = padExact(line2, width)
-					}
-					lines = append(lines, line2)
-				}
-			}
-		}
+	if m.diffFocus && width > 1 {
+		// Reserve a gutter column for the cursor marker
+		width--
 	}
-	return lines
+	lines, starts := RenderDiff(m.theme, f, m.rows, width, m.renderOptions())
+	if m.diffFocus {
+		lines = m.addDiffGutter(lines, starts)
+	}
+	return lines, starts
+}
 
+// renderOptions renders the diff pane as toggled, with the highlighting of
+// the shown file only.
+func (m model) renderOptions() RenderOptions {
+	o := RenderOptions{
+		SideBySide:  m.sideBySide,
+		Wrap:        m.wrapLines,
+		LineNumbers: m.lineNumbers,
+		XOffset:     m.rightXOffset,
+		Hunk:        m.hunkSeparator,
+	}
+	if m.synPath == m.rowsPath {
+		o.SynOld, o.SynNew = m.synOld, m.synNew
+	}
+	return o
 }
 
 func (m *model) openSearch() {
@@ -2676,6 +3041,10 @@ func (m model) selectedPaths() []string {
 	for _, f := range m.cwFiles {
 		if m.cwSelected[f.Path] {
 			out = append(out, f.Path)
+			// A move made in the working tree also stages the old path's deletion.
+			if f.Renamed && f.Untracked {
+				out = append(out, f.OldPath)
+			}
 		}
 	}
 	return out
@@ -2732,97 +3101,6 @@ func (m model) colorizeRight(r diffview.Row) string {
 	}
 }
 
-// renderSideCell renders a left or right cell with a colored marker and padding.
-// side is "left" or "right". width is the total cell width.
-func (m model) renderSideCell(r diffview.Row, side string, width int) string {
-	marker := " "
-	content := ""
-	switch side {
-	case "left":
-		content = r.Left
-		switch r.Kind {
-		case diffview.RowContext:
-			marker = " "
-		case diffview.RowDel, diffview.RowReplace:
-			marker = m.theme.DelText("-")
-			content = m.theme.DelText(content)
-		case diffview.RowAdd:
-			marker = " "
-			content = ""
-		}
-	case "right":
-		content = r.Right
-		switch r.Kind {
-		case diffview.RowContext:
-			marker = " "
-		case diffview.RowAdd, diffview.RowReplace:
-			marker = m.theme.AddText("+")
-			content = m.theme.AddText(content)
-		case diffview.RowDel:
-			marker = " "
-			content = ""
-		}
-	}
-	// Reserve 2 cols: marker + space
-	if width <= 2 {
-		return ansi.Truncate(marker+" ", width, "")
-	}
-	bodyW := width - 2
-
-	clipped := sliceANSI(content, m.rightXOffset, bodyW)
-
-	return marker + " " + clipped
-}
-
-// renderSideCellWrap renders a cell like renderSideCell but wraps the content
-// to the given width and returns multiple visual lines. The marker is repeated
-// on each wrapped line.
-func (m model) renderSideCellWrap(r diffview.Row, side string, width int) []string {
-	marker := " "
-	content := ""
-	switch side {
-	case "left":
-		content = r.Left
-		switch r.Kind {
-		case diffview.RowContext:
-			marker = " "
-		case diffview.RowDel, diffview.RowReplace:
-			marker = m.theme.DelText("-")
-			content = m.theme.DelText(content)
-		case diffview.RowAdd:
-			marker = " "
-			content = ""
-		}
-	case "right":
-		content = r.Right
-		switch r.Kind {
-		case diffview.RowContext:
-			marker = " "
-		case diffview.RowAdd, diffview.RowReplace:
-			marker = m.theme.AddText("+")
-			content = m.theme.AddText(content)
-		case diffview.RowDel:
-			marker = " "
-			content = ""
-		}
-	}
-	// Reserve 2 cols for marker and a space
-	if width <= 2 {
-		return []string{ansi.Truncate(marker+" ", width, "")}
-	}
-	bodyW := width - 2
-	wrapped := ansi.Hardwrap(content, bodyW, false)
-	parts := strings.Split(wrapped, "\n")
-	out := make([]string, 0, len(parts))
-	for _, p := range parts {
-		out = append(out, marker+" "+padExact(p, bodyW))
-	}
-	if len(out) == 0 {
-		out = append(out, marker+" "+strings.Repeat(" ", bodyW))
-	}
-	return out
-}
-
 // sliceANSI returns a substring of s starting at visual column `start` with at most `w` columns, preserving ANSI escapes.
 func sliceANSI(s string, start, w int) string {
 	if start <= 0 {
@@ -1,13 +1,21 @@
 package tui
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
 	"github.com/interpretive-systems/diffium/internal/diffview"
 	"github.com/interpretive-systems/diffium/internal/gitx"
+	"github.com/interpretive-systems/diffium/internal/review"
+	"github.com/interpretive-systems/diffium/internal/syntax"
+	"github.com/muesli/termenv"
 )
 
 func baseModelForTest() model {
@@ -70,3 +78,694 @@ func TestView_Inline_Render(t *testing.T) {
 		t.Fatalf("expected inline deleted line, got: %q", plain)
 	}
 }
+
+func TestView_LineNumbers_Render(t *testing.T) {
+	m := baseModelForTest()
+	m.sideBySide = false
+	m.lineNumbers = true
+	m.rows = diffview.BuildRowsFromUnified(sampleUnified())
+	(&m).recalcViewport()
+	plain := ansi.Strip(m.View())
+
+	if !strings.Contains(plain, "  2 + line2 changed") {
+		t.Fatalf("expected numbered added line, got: %q", plain)
+	}
+	if !strings.Contains(plain, "2   - line2") {
+		t.Fatalf("expected numbered deleted line, got: %q", plain)
+	}
+}
+
+func TestDiffFocus_VisualSelection(t *testing.T) {
+	m := baseModelForTest()
+	m.sideBySide = false
+	m.rows = diffview.BuildRowsFromUnified("@@ -1,3 +1,4 @@\n line1\n-line2\n+line2 changed\n+line3 new\n line4\n")
+	(&m).recalcViewport()
+	(&m).focusDiff()
+	if m.rows[m.diffCursor].Kind != diffview.RowReplace {
+		t.Fatalf("expected cursor on first change, got %+v", m.rows[m.diffCursor])
+	}
+	m.visualActive = true
+	m.visualAnchor = m.diffCursor
+	(&m).moveDiffCursor(1)
+	from, to := m.selectionRange()
+	if to-from != 2 {
+		t.Fatalf("expected two selected rows, got [%d,%d)", from, to)
+	}
+	(&m).recalcViewport()
+	plain := ansi.Strip(m.View())
+	if strings.Count(plain, "▌") != 3 { // replace row renders as two lines inline
+		t.Fatalf("expected gutter marks on selected lines, got: %q", plain)
+	}
+}
+
+func TestDiscard_StagedMode(t *testing.T) {
+	m := baseModelForTest()
+	m.rows = diffview.BuildRowsFromUnified(sampleUnified())
+	(&m).recalcViewport()
+	(&m).focusDiff()
+	discard := func(m model) model {
+		nm, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+		return nm.(model)
+	}
+
+	if got := discard(m); !got.showDiscard {
+		t.Fatalf("expected the discard wizard in HEAD mode, got status %q", got.status)
+	}
+	m.diffMode = "staged"
+	got := discard(m)
+	if got.showDiscard || !strings.Contains(got.status, "unstage them first") {
+		t.Fatalf("expected discard to be refused in staged mode, got status %q", got.status)
+	}
+}
+
+func TestConflictView_Render(t *testing.T) {
+	m := baseModelForTest()
+	m.width = 120
+	m.files[0].Conflicted = true
+	m.conflictPath = "file1.txt"
+	m.conflict = diffview.ParseConflicts("<<<<<<< HEAD\nmine\n||||||| base\norig\n=======\nyours\n>>>>>>> feature\n")
+	(&m).focusDiff()
+	(&m).recalcViewport()
+	plain := ansi.Strip(m.View())
+	for _, want := range []string{"!M file1.txt", "conflict 1/1", "ours (HEAD)", "theirs (feature)", "mine", "orig", "yours"} {
+		if !strings.Contains(plain, want) {
+			t.Fatalf("expected %q in view:\n%s", want, plain)
+		}
+	}
+	if !m.diffFocus {
+		t.Fatalf("expected the conflict view to take focus")
+	}
+}
+
+func TestView_SyntaxHighlight(t *testing.T) {
+	m := baseModelForTest()
+	m.files[0].Path = "main.go"
+	m.sideBySide = true
+	m.rows = diffview.BuildRowsFromUnified("@@ -1,2 +1,2 @@\n func f() {\n-\treturn \"é\" // old\n+\treturn \"é\" // new\n")
+	m.rowsPath = "main.go"
+	src := "func f() {\n\treturn \"é\" // new\n}\n"
+	m.synPath, m.synNew = "main.go", syntax.Highlight(syntax.Detect("main.go", ""), src)
+	if got := (rowRenderer{RenderOptions: m.renderOptions()}).syntaxRight(m.rows[2]); len(got) != 3 {
+		t.Fatalf("expected keyword, string and comment spans, got %+v", got)
+	}
+	m.rowsPath = "other.go"
+	if (rowRenderer{RenderOptions: m.renderOptions()}).syntaxRight(m.rows[2]) != nil {
+		t.Fatalf("highlighting of another file must not be used")
+	}
+	m.rowsPath = "main.go"
+	(&m).recalcViewport()
+	plain := ansi.Strip(m.View())
+	if !strings.Contains(plain, `return "é" // new`) {
+		t.Fatalf("highlighting must not change the text, got: %q", plain)
+	}
+}
+
+func TestTheme_SyntaxOnChangedLines(t *testing.T) {
+	defer lipgloss.SetColorProfile(lipgloss.ColorProfile())
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	m := baseModelForTest()
+	m.files[0].Path = "main.go"
+	m.sideBySide = false
+	m.rows = diffview.BuildRowsFromUnified("@@ -1,1 +1,2 @@\n func f() {\n+\treturn nil\n")
+	m.rowsPath = "main.go"
+	m.synPath, m.synNew = "main.go", syntax.Highlight(syntax.Detect("main.go", ""), "func f() {\n\treturn nil\n")
+	(&m).recalcViewport()
+	th := m.theme
+	// with the default theme the added keyword is highlighted, on the add
+	// background
+	keyword := lipgloss.NewStyle().Foreground(lipgloss.Color(th.Syntax.Keyword)).Background(lipgloss.Color(th.AddBgColor)).Render("return")
+	if out := m.View(); !strings.Contains(out, keyword) {
+		t.Fatalf("expected the added keyword highlighted, got %q", out)
+	}
+
+	// without a background the add color marks the line instead
+	syn := []syntax.Span{{Start: 0, End: 6, Kind: syntax.Keyword}}
+	add := lipgloss.NewStyle().Foreground(lipgloss.Color(th.AddColor)).Render("return")
+	th.AddBgColor = ""
+	if got := th.AddTextSpans("return x", nil, syn); !strings.HasPrefix(got, add) {
+		t.Fatalf("expected the added keyword in the add color, got %q", got)
+	}
+}
+
+func TestEditLineAndCommand(t *testing.T) {
+	m := baseModelForTest()
+	m.rows = diffview.BuildRowsFromUnified("@@ -10,3 +10,2 @@\n ctx\n-gone\n keep\n")
+	m.rowsPath = "file1.txt"
+	m.diffFocus = true
+	m.diffCursor = 2 // the deleted line
+	if got := m.editLine(); got != 11 {
+		t.Fatalf("expected the line after the deletion, got %d", got)
+	}
+	m.rowsPath = "file2.txt"
+	if got := m.editLine(); got != 0 {
+		t.Fatalf("expected no line while another file's diff is shown, got %d", got)
+	}
+
+	cf := diffview.ParseConflicts("a\n<<<<<<< HEAD\nx\n=======\ny\n>>>>>>> b\nmid\n<<<<<<< HEAD\nz\n=======\nw\n>>>>>>> b\n")
+	if got := conflictBlockLine(cf, 1); got != 8 {
+		t.Fatalf("expected second block at line 8, got %d", got)
+	}
+
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "true -f")
+	cmd, err := editorCommand("/repo/a.go", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(cmd.Args, " "); got != "true -f +42 /repo/a.go" {
+		t.Fatalf("unexpected editor command %q", got)
+	}
+}
+
+func TestEditLine_Unfocused(t *testing.T) {
+	m := baseModelForTest()
+	var b strings.Builder
+	b.WriteString("@@ -1,2 +1,2 @@\n-a\n+A\n b\n")
+	b.WriteString("@@ -20,12 +20,12 @@\n")
+	for i := 0; i < 10; i++ {
+		b.WriteString(" ctx\n")
+	}
+	b.WriteString("-c\n+C\n d\n")
+	m.rows = diffview.BuildRowsFromUnified(b.String())
+	m.rowsPath = "file1.txt"
+	(&m).recalcViewport()
+	m.diffCursor = len(m.rows) - 1 // stale cursor, ignored while unfocused
+	if got := m.editLine(); got != 1 {
+		t.Fatalf("expected the first change in view, got %d", got)
+	}
+	m.rightVP.SetYOffset(5)
+	if got := m.editLine(); got != 30 {
+		t.Fatalf("expected the first change below the scrolled view top, got %d", got)
+	}
+	m.rows = diffview.BuildRowsFromUnified("@@ -1,31 +1,31 @@\n-a\n+A\n" + strings.Repeat(" ctx\n", 30))
+	(&m).recalcViewport()
+	m.rightVP.SetYOffset(10) // only context in view
+	if m.rightVP.YOffset == 0 {
+		t.Fatalf("expected the view scrolled")
+	}
+	if got := m.editLine(); got != 1 {
+		t.Fatalf("expected the first change in the file, got %d", got)
+	}
+}
+
+func TestFindings_MarkAndFormat(t *testing.T) {
+	m := baseModelForTest()
+	m.rows = diffview.BuildRowsFromUnified("@@ -1,3 +1,3 @@\n line1\n-x := old\n+x := new\n line3\n")
+	m.rowsPath = "file1.txt"
+	(&m).recalcViewport()
+	(&m).focusDiff()
+
+	nm, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	m = nm.(model)
+	if !m.fdActive {
+		t.Fatal("expected the finding prompt")
+	}
+	m.fdInput.SetValue("use the constant")
+	nm, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = nm.(model)
+	(&m).moveDiffCursor(1)
+	nm, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	m = nm.(model)
+	nm, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = nm.(model)
+
+	want := "file1.txt:2:6: use the constant\nfile1.txt:3:1: line3\n"
+	if got := formatFindings(m.findings); got != want {
+		t.Fatalf("unexpected findings:\n%s", got)
+	}
+	m.diffCursor = 1 // line1: the cursor hides a row's mark
+	(&m).recalcViewport()
+	if plain := ansi.Strip(m.View()); strings.Count(plain, "•") != 2 {
+		t.Fatalf("expected both rows marked, got: %q", plain)
+	}
+
+	// m again on a marked row removes it
+	m.diffCursor = 3
+	nm, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	m = nm.(model)
+	if len(m.findings) != 1 || m.fdActive {
+		t.Fatalf("expected the finding to be removed, got %+v", m.findings)
+	}
+}
+
+func TestReview_OpenCorruptMarks(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	state := filepath.Join(dir, ".git", "diffium")
+	if err := os.MkdirAll(state, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(state, "review.json"), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := baseModelForTest()
+	m.repoRoot = dir
+	(&m).openReview()
+	if m.review == nil || !strings.Contains(m.status, "review marks unreadable") {
+		t.Fatalf("expected empty marks and an error in the status, got review=%v status=%q", m.review, m.status)
+	}
+	m.marks().Mark("file1.txt", "abc")
+	if err := m.review.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := review.Load(dir); err != nil || !s.Scope(review.Worktree).Reviewed("file1.txt", "abc") {
+		t.Fatalf("expected the saved marks to replace the corrupt file, err=%v", err)
+	}
+}
+
+func TestReview_MarkHunkAndFile(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	s, err := review.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := baseModelForTest()
+	m.review = s
+	m.rows = diffview.BuildRowsFromUnified("@@ -1,2 +1,2 @@\n a\n-b\n+B\n@@ -10,2 +10,2 @@\n j\n-k\n+K\n")
+	m.rowsPath = "file1.txt"
+	(&m).recalcViewport()
+	(&m).focusDiff()
+
+	nm, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	m = nm.(model)
+	if m.reviewed["file1.txt"] {
+		t.Fatal("one of two hunks reviewed should not mark the file")
+	}
+	plain := ansi.Strip(m.View())
+	if strings.Count(plain, "✓") != 1 || !strings.Contains(plain, "│ ✓·") {
+		t.Fatalf("expected the first hunk checked, got: %q", plain)
+	}
+
+	m.diffFocus = false
+	nm, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	m = nm.(model)
+	if !m.reviewed["file1.txt"] || !strings.Contains(ansi.Strip(m.View()), "file1.txt ✓") {
+		t.Fatalf("expected the file checked, got: %q", ansi.Strip(m.View()))
+	}
+
+	// editing the second hunk clears its mark, and with it the file's
+	nm, _ = m.Update(diffMsg{path: "file1.txt", rows: diffview.BuildRowsFromUnified("@@ -1,2 +1,2 @@\n a\n-b\n+B\n@@ -10,2 +10,2 @@\n j\n-k\n+KK\n")})
+	m = nm.(model)
+	if m.reviewed["file1.txt"] {
+		t.Fatal("expected an edited hunk to clear the file's mark")
+	}
+	s, _ = review.Load(dir)
+	if paths := s.Scope(review.Worktree).Paths(); len(paths) != 1 {
+		t.Fatalf("expected the first hunk's mark to be saved, got %v", paths)
+	}
+
+	// marks made in the staged view survive pruning against the working tree
+	m.diffMode = "staged"
+	nm, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	m = nm.(model)
+	m.diffMode = "head"
+	m, _ = m.reviewResult(reviewMsg{scope: review.Worktree, hunks: map[string][]string{"file1.txt": nil}, prune: true})
+	if m.review.Scope(review.Worktree).Has("file1.txt") || !m.review.Scope(review.Index).Has("file1.txt") {
+		t.Fatal("expected only the working tree marks pruned")
+	}
+}
+
+func TestSnapshotMode(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gitx.TakeSnapshot(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := baseModelForTest()
+	m.repoRoot = dir
+	m.files = nil
+	m.diffMode = "snapshot"
+	nm, _ := m.Update(m.reloadFiles()())
+	m = nm.(model)
+	if len(m.files) != 2 || !strings.HasPrefix(m.modeLabel(), "since snapshot ") {
+		t.Fatalf("expected both files since the snapshot, got %+v (%s)", m.files, m.modeLabel())
+	}
+	nm, _ = m.Update(loadCurrentDiff(m)().(tea.BatchMsg)[0]())
+	m = nm.(model)
+	if got := rowText(m.rows[len(m.rows)-1]); got != "two" {
+		t.Fatalf("expected the line added since the snapshot, got %q", got)
+	}
+
+	(&m).focusDiff()
+	nm, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" ")})
+	m = nm.(model)
+	if !strings.Contains(m.status, "read-only") {
+		t.Fatalf("expected staging to be refused, got status %q", m.status)
+	}
+}
+
+func TestSnapshotMode_NoSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := baseModelForTest()
+	m.repoRoot = dir
+	m.width = 100
+	m.leftWidth = 30
+	m.files = nil
+	m.diffMode = "snapshot"
+	nm, _ := m.Update(m.reloadFiles()())
+	m = nm.(model)
+	if _, ok, _ := gitx.LatestSnapshot(dir); ok {
+		t.Fatal("entering snapshot mode must not take a snapshot")
+	}
+	(&m).recalcViewport()
+	if plain := ansi.Strip(m.View()); len(m.files) != 0 || !strings.Contains(plain, "No snapshot yet — press S") {
+		t.Fatalf("expected the empty snapshot state, got %+v:\n%s", m.files, plain)
+	}
+
+	nm, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("S")})
+	m = nm.(model)
+	nm, _ = m.Update(cmd())
+	m = nm.(model)
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	nm, _ = m.Update(m.reloadFiles()())
+	m = nm.(model)
+	if m.noSnapshot || len(m.files) != 1 || !strings.HasPrefix(m.modeLabel(), "since snapshot ") {
+		t.Fatalf("expected the change since the new snapshot, got %+v (%s)", m.files, m.modeLabel())
+	}
+}
+
+func TestCheckpoint_Coalesces(t *testing.T) {
+	m := baseModelForTest()
+	m.checkpoints = true
+	if (&m).checkpoint() == nil {
+		t.Fatal("expected a checkpoint to start")
+	}
+	for i := 0; i < 3; i++ {
+		if (&m).checkpoint() != nil {
+			t.Fatal("expected no second checkpoint while one runs")
+		}
+	}
+	m, cmd := m.checkpointResult(checkpointMsg{})
+	if cmd == nil || !m.checkpointing {
+		t.Fatal("expected one more checkpoint for the changes made meanwhile")
+	}
+	if m, cmd = m.checkpointResult(checkpointMsg{}); cmd != nil || m.checkpointing {
+		t.Fatal("expected no further checkpoint")
+	}
+}
+
+func TestTimeline_ScrubAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	path := filepath.Join(dir, "a.txt")
+	for _, content := range []string{"one\n", "one\ntwo\n"} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := gitx.Checkpoint(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := baseModelForTest()
+	m.repoRoot = dir
+	m.files = nil
+	m.checkpoints = true
+	key := func(k string) tea.Cmd {
+		nm, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		m = nm.(model)
+		return cmd
+	}
+	key("T")
+	nm, _ := m.Update(loadTimeline(dir)())
+	m = nm.(model)
+	if !m.showTimeline || len(m.tlList) != 2 || m.tlBrowsing {
+		t.Fatalf("expected an open timeline with two checkpoints, got %d (browsing %v)", len(m.tlList), m.tlBrowsing)
+	}
+
+	key("j")
+	if !m.tlBrowsing || m.diffRange.Base != m.tlList[1].ID || m.diffRange.Head != m.tlList[0].ID {
+		t.Fatalf("expected the older checkpoint against the latest, got %+v", m.diffRange)
+	}
+	nm, _ = m.Update(m.reloadFiles()())
+	m = nm.(model)
+	if len(m.files) != 1 || m.files[0].Path != "a.txt" {
+		t.Fatalf("expected a.txt between the checkpoints, got %+v", m.files)
+	}
+
+	msg := key("r")().(patchResultMsg)
+	if msg.err != nil {
+		t.Fatal(msg.err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "one\n" {
+		t.Fatalf("expected a.txt restored to the older checkpoint, got %q", b)
+	}
+
+	key(" ")
+	key("k")
+	if m.tlPin != m.tlList[1].ID || m.diffRange.Base != m.tlList[1].ID || m.diffRange.Head != m.tlList[0].ID {
+		t.Fatalf("expected the pinned checkpoint as the base, got %+v", m.diffRange)
+	}
+
+	nm, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = nm.(model)
+	if m.showTimeline || m.tlBrowsing || m.inRange() {
+		t.Fatalf("expected esc to close the timeline and return to the working tree, got %+v", m.diffRange)
+	}
+}
+
+func TestStashWizard(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	if err := os.WriteFile(path, []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := baseModelForTest()
+	m.repoRoot = dir
+	m.files = nil
+	update := func(msg tea.Msg) tea.Cmd {
+		nm, cmd := m.Update(msg)
+		m = nm.(model)
+		return cmd
+	}
+	key := func(k string) tea.Cmd {
+		return update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+	}
+
+	// create a stash of the tracked change only
+	key("z")
+	update(loadStashes(dir)())
+	key("n")
+	update(loadStashFiles(dir)())
+	if len(m.stFiles) != 2 || m.stSelected["new.txt"] {
+		t.Fatalf("expected both changes listed, untracked unselected, got %+v %v", m.stFiles, m.stSelected)
+	}
+	key("enter")
+	m.stInput.SetValue("wip")
+	res := update(tea.KeyMsg{Type: tea.KeyEnter})().(stashResultMsg)
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	update(res)
+	update(loadStashes(dir)())
+	if len(m.stList) != 1 || !strings.HasSuffix(m.stList[0].Subject, ": wip") {
+		t.Fatalf("expected the new stash, got %+v", m.stList)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "one\n" {
+		t.Fatalf("expected a.txt stashed, got %q", b)
+	}
+
+	// the selected stash is shown in the panes
+	update(m.previewStash()())
+	if !strings.HasPrefix(m.diffRange.Label, "stash@{0}") {
+		t.Fatalf("expected the stash in the panes, got %+v", m.diffRange)
+	}
+	update(m.reloadFiles()())
+	if len(m.files) != 1 || m.files[0].Path != "a.txt" {
+		t.Fatalf("expected a.txt in the stash, got %+v", m.files)
+	}
+
+	// drop asks twice, apply once; apply closes the wizard
+	key("d")
+	if cmd := key("y"); cmd != nil || m.stStep != 1 {
+		t.Fatalf("expected the first drop confirmation to need enter, got step %d", m.stStep)
+	}
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.stStep != 2 {
+		t.Fatalf("expected the final drop confirmation, got step %d", m.stStep)
+	}
+	key("b")
+	key("b")
+	if key("a"); m.stStep != 1 || m.stVerb != "apply" {
+		t.Fatalf("expected apply to ask first, got step %d %q", m.stStep, m.stVerb)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "one\n" {
+		t.Fatalf("applied before the confirmation, a.txt is %q", b)
+	}
+	update(key("y")())
+	if m.showStash || m.inRange() || m.status != "applied stash@{0}" {
+		t.Fatalf("expected the wizard closed on the working tree, got %v %+v %q", m.showStash, m.diffRange, m.status)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "one\ntwo\n" {
+		t.Fatalf("expected a.txt applied, got %q", b)
+	}
+}
+
+func TestStageHunk_PartiallyStaged(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return string(out)
+	}
+	var lines []string
+	for c := 'a'; c <= 't'; c++ {
+		lines = append(lines, string(c))
+	}
+	path := filepath.Join(dir, "f.txt")
+	writeLines := func() {
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeLines()
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	lines[1], lines[18] = "B", "S"
+	writeLines()
+	git("add", "f.txt")
+	lines[2], lines[10] = "C", "K"
+	writeLines()
+
+	m := baseModelForTest()
+	m.repoRoot = dir
+	m.diffMode = "head"
+	m.files = nil
+	nm, _ := m.Update(m.reloadFiles()())
+	m = nm.(model)
+	nm, _ = m.Update(loadCurrentDiff(m)().(tea.BatchMsg)[0]())
+	m = nm.(model)
+	(&m).recalcViewport()
+	(&m).focusDiff()
+	stage := func() patchResultMsg {
+		nm, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" ")})
+		m = nm.(model)
+		return cmd().(patchResultMsg)
+	}
+
+	// b -> B is staged, c -> C in the same hunk is not
+	if res := stage(); res.err != nil {
+		t.Fatalf("staging the partially staged hunk: %v", res.err)
+	}
+	m.status = "stale"
+	(&m).jumpHunk(1)
+	if res := stage(); res.err != nil {
+		t.Fatalf("staging the second hunk: %v", res.err)
+	}
+	if m.status != "" {
+		t.Fatalf("expected the status cleared by the next action, got %q", m.status)
+	}
+	// s -> S is fully staged already
+	(&m).jumpHunk(1)
+	if res := stage(); res.err == nil || !strings.Contains(res.err.Error(), "already staged") {
+		t.Fatalf("expected the staged hunk to be refused, got %+v", res)
+	}
+	if out := git("diff"); out != "" {
+		t.Fatalf("expected nothing left unstaged, got:\n%s", out)
+	}
+	if out := git("diff", "--cached"); !strings.Contains(out, "+C") || !strings.Contains(out, "+K") {
+		t.Fatalf("expected all changes staged, got:\n%s", out)
+	}
+}
+
+func TestUndo_Confirm(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+		{"branch", "other"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if msg := runCheckout(dir, "missing")().(branchResultMsg); msg.err == nil {
+		t.Fatal("expected checking out a missing branch to fail")
+	}
+	if msg := runCheckout(dir, "other")().(branchResultMsg); msg.err != nil {
+		t.Fatal(msg.err)
+	}
+
+	m := baseModelForTest()
+	m.repoRoot = dir
+	press := func(k string) tea.Cmd {
+		nm, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		m = nm.(model)
+		return cmd
+	}
+	press("U")
+	nm, _ := m.Update(loadLastUndo(dir)())
+	m = nm.(model)
+	// the failed checkout was not recorded
+	if !m.showUndo || m.udOp.String() != "checkout of other" {
+		t.Fatalf("expected to be asked about the checkout, got %+v", m.udOp)
+	}
+	if cmd := press("y"); cmd != nil || m.udStep != 0 {
+		t.Fatalf("y must not undo before the first confirmation")
+	}
+	nm, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = nm.(model)
+	if m.udStep != 1 {
+		t.Fatalf("expected the final confirmation, got step %d", m.udStep)
+	}
+	if b, _ := gitx.CurrentBranch(dir); b != "other" {
+		t.Fatalf("undid before the final confirmation, on %s", b)
+	}
+	press("y")
+	nm, _ = m.Update(runUndo(dir, false)())
+	m = nm.(model)
+	if m.showUndo || m.status != "undid checkout of other" {
+		t.Fatalf("expected the undo to finish, got status %q (%s)", m.status, m.udErr)
+	}
+	if b, _ := gitx.CurrentBranch(dir); b != "main" {
+		t.Fatalf("expected to be back on main, got %s", b)
+	}
+}
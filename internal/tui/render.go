@@ -0,0 +1,322 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/interpretive-systems/diffium/internal/diffview"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+	"github.com/interpretive-systems/diffium/internal/syntax"
+)
+
+// --- Diff rendering, shared by the diff pane and `diffium diff` ---
+
+// RenderOptions configures RenderDiff.
+type RenderOptions struct {
+	// SideBySide renders two columns instead of one.
+	SideBySide bool
+	// Wrap wraps long lines; otherwise they are cut, XOffset columns in.
+	Wrap    bool
+	XOffset int
+	// LineNumbers adds the line-number gutter.
+	LineNumbers bool
+	// SynOld and SynNew hold the highlighting of the old and new file, per
+	// line; nil renders without syntax colors.
+	SynOld, SynNew [][]syntax.Span
+	// Hunk renders the rule above the hunk at row i; nil draws a faint one.
+	Hunk func(i, width int) string
+}
+
+// RenderDiff renders the rows of f's diff at width, with a rename header
+// when f was renamed or copied. It also returns, for each row, the index of
+// its first rendered line.
+func RenderDiff(t Theme, f gitx.FileChange, rows []diffview.Row, width int, o RenderOptions) ([]string, []int) {
+	lines := make([]string, 0, 1024)
+	if f.Binary {
+		lines = append(lines, lipgloss.NewStyle().Faint(true).Render("(Binary file; no text diff)"))
+		return lines, nil
+	}
+	if h := RenameHeader(f); h != "" {
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Render(h))
+		if !hasHunks(rows) {
+			lines = append(lines, lipgloss.NewStyle().Faint(true).Render("(no content changes)"))
+		}
+	}
+	rr := rowRenderer{theme: t, RenderOptions: o}
+	if rr.Hunk == nil {
+		rr.Hunk = func(_, width int) string { return hunkRule(width) }
+	}
+	starts := make([]int, len(rows))
+	numW := 0
+	if o.LineNumbers {
+		numW = lineNumberWidth(rows)
+	}
+	if o.SideBySide {
+		colsW := (width - 1) / 2
+		if colsW < 10 {
+			colsW = 10
+		}
+		cellW := colsW
+		if numW > 0 {
+			cellW = colsW - numW - 1
+			if cellW < 4 {
+				cellW = 4
+			}
+		}
+		mid := t.DividerText("│")
+		for i, r := range rows {
+			starts[i] = len(lines)
+			switch r.Kind {
+			case diffview.RowHunk:
+				// subtle separator fills full width
+				lines = append(lines, rr.Hunk(i, width))
+			case diffview.RowMeta:
+				// skip
+			default:
+				lNum, rNum := lineNumber(r.LeftLine, numW), lineNumber(r.RightLine, numW)
+				if o.Wrap {
+					lLines := rr.sideCellWrap(r, "left", cellW)
+					rLines := rr.sideCellWrap(r, "right", cellW)
+					n := len(lLines)
+					if len(rLines) > n {
+						n = len(rLines)
+					}
+					for i := 0; i < n; i++ {
+						var l, rc string
+						if i < len(lLines) {
+							l = lLines[i]
+						} else {
+							l = strings.Repeat(" ", cellW)
+						}
+						if i < len(rLines) {
+							rc = rLines[i]
+						} else {
+							rc = strings.Repeat(" ", cellW)
+						}
+						lines = append(lines, lNum+l+mid+rNum+rc)
+						lNum, rNum = lineNumber(0, numW), lineNumber(0, numW)
+					}
+				} else {
+					l := padExact(rr.sideCell(r, "left", cellW), cellW)
+					rc := padExact(rr.sideCell(r, "right", cellW), cellW)
+					lines = append(lines, lNum+l+mid+rNum+rc)
+				}
+			}
+		}
+		return lines, starts
+	}
+	// emit appends one diff line after the line-number gutter,
+	// wrapping or horizontally scrolling it.
+	emit := func(oldNum, newNum int, base string) {
+		gutter := ""
+		if numW > 0 {
+			gutter = lineNumber(oldNum, numW) + lineNumber(newNum, numW)
+		}
+		w := width - lipgloss.Width(gutter)
+		if o.Wrap {
+			for j, l := range strings.Split(ansi.Hardwrap(base, w, false), "\n") {
+				if j > 0 && gutter != "" {
+					gutter = lineNumber(0, numW) + lineNumber(0, numW)
+				}
+				lines = append(lines, gutter+l)
+			}
+			return
+		}
+		line := base
+		if o.XOffset > 0 {
+			line = sliceANSI(line, o.XOffset, w)
+			line = padExact(line, w)
+		}
+		lines = append(lines, gutter+line)
+	}
+	for i, r := range rows {
+		starts[i] = len(lines)
+		switch r.Kind {
+		case diffview.RowHunk:
+			lines = append(lines, rr.Hunk(i, width))
+		case diffview.RowContext:
+			emit(r.LeftLine, r.RightLine, "  "+t.CodeText(r.Left, rr.syntaxRight(r)))
+		case diffview.RowAdd:
+			emit(0, r.RightLine, t.AddText("+ ")+t.AddTextSpans(r.Right, nil, rr.syntaxRight(r)))
+		case diffview.RowDel:
+			emit(r.LeftLine, 0, t.DelText("- ")+t.DelTextSpans(r.Left, nil, rr.syntaxLeft(r)))
+		case diffview.RowReplace:
+			emit(r.LeftLine, 0, t.DelText("- ")+t.DelTextSpans(r.Left, r.LeftSpans, rr.syntaxLeft(r)))
+			emit(0, r.RightLine, t.AddText("+ ")+t.AddTextSpans(r.Right, r.RightSpans, rr.syntaxRight(r)))
+		}
+	}
+	return lines, starts
+}
+
+// hunkRule is the faint rule drawn above a hunk.
+func hunkRule(width int) string {
+	return lipgloss.NewStyle().Faint(true).Render(strings.Repeat("·", width))
+}
+
+// rowRenderer renders single rows for RenderDiff.
+type rowRenderer struct {
+	theme Theme
+	RenderOptions
+}
+
+// syntaxLeft and syntaxRight return the highlighting of a row's old and new
+// line, or nil when there is none.
+func (rr rowRenderer) syntaxLeft(r diffview.Row) []syntax.Span {
+	if r.LeftLine <= 0 || r.LeftLine > len(rr.SynOld) {
+		return nil
+	}
+	return rr.SynOld[r.LeftLine-1]
+}
+
+func (rr rowRenderer) syntaxRight(r diffview.Row) []syntax.Span {
+	if r.RightLine <= 0 || r.RightLine > len(rr.SynNew) {
+		return nil
+	}
+	return rr.SynNew[r.RightLine-1]
+}
+
+// sideCellContent returns the colored marker and content of a left or right
+// cell. side is "left" or "right".
+func (rr rowRenderer) sideCellContent(r diffview.Row, side string) (marker, content string) {
+	marker = " "
+	switch side {
+	case "left":
+		switch r.Kind {
+		case diffview.RowContext:
+			content = rr.theme.CodeText(r.Left, rr.syntaxLeft(r))
+		case diffview.RowDel, diffview.RowReplace:
+			marker = rr.theme.DelText("-")
+			content = rr.theme.DelTextSpans(r.Left, r.LeftSpans, rr.syntaxLeft(r))
+		}
+	case "right":
+		switch r.Kind {
+		case diffview.RowContext:
+			content = rr.theme.CodeText(r.Right, rr.syntaxRight(r))
+		case diffview.RowAdd, diffview.RowReplace:
+			marker = rr.theme.AddText("+")
+			content = rr.theme.AddTextSpans(r.Right, r.RightSpans, rr.syntaxRight(r))
+		}
+	}
+	return marker, content
+}
+
+// sideCell renders a left or right cell with a colored marker, cut to width
+// after XOffset columns.
+func (rr rowRenderer) sideCell(r diffview.Row, side string, width int) string {
+	marker, content := rr.sideCellContent(r, side)
+	// Reserve 2 cols: marker + space
+	if width <= 2 {
+		return ansi.Truncate(marker+" ", width, "")
+	}
+	return marker + " " + sliceANSI(content, rr.XOffset, width-2)
+}
+
+// sideCellWrap renders a cell like sideCell but wraps the content to width
+// and returns multiple visual lines. The marker is repeated on each wrapped
+// line.
+func (rr rowRenderer) sideCellWrap(r diffview.Row, side string, width int) []string {
+	marker, content := rr.sideCellContent(r, side)
+	// Reserve 2 cols for marker and a space
+	if width <= 2 {
+		return []string{ansi.Truncate(marker+" ", width, "")}
+	}
+	bodyW := width - 2
+	parts := strings.Split(ansi.Hardwrap(content, bodyW, false), "\n")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		out = append(out, marker+" "+padExact(p, bodyW))
+	}
+	if len(out) == 0 {
+		out = append(out, marker+" "+strings.Repeat(" ", bodyW))
+	}
+	return out
+}
+
+// lineNumberWidth returns the digits needed for the largest line number.
+func lineNumberWidth(rows []diffview.Row) int {
+	maxN := 0
+	for _, r := range rows {
+		maxN = max(maxN, r.LeftLine, r.RightLine)
+	}
+	return len(strconv.Itoa(maxN))
+}
+
+// lineNumber renders n right-aligned in a gutter of width w plus a space;
+// zero renders blank. It returns "" when the gutter is off.
+func lineNumber(n, w int) string {
+	if w == 0 {
+		return ""
+	}
+	if n == 0 {
+		return strings.Repeat(" ", w+1)
+	}
+	return lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("%*d", w, n)) + " "
+}
+
+// StatusLabel returns the status letters shown before a file, such as "SM"
+// for a file with staged and unstaged changes.
+func StatusLabel(f gitx.FileChange) string {
+	var tags []string
+	if f.Conflicted {
+		tags = append(tags, "!")
+	}
+	switch {
+	case f.Renamed:
+		tags = append(tags, "R")
+	case f.Copied:
+		tags = append(tags, "C")
+	case f.Untracked:
+		tags = append(tags, "U")
+	}
+	if f.Added {
+		tags = append(tags, "A")
+	}
+	if f.Deleted {
+		tags = append(tags, "D")
+	}
+	if f.Staged {
+		tags = append(tags, "S")
+	}
+	if f.Unstaged {
+		tags = append(tags, "M")
+	}
+	if len(tags) == 0 {
+		// only range listings have files without flags
+		return "M"
+	}
+	return strings.Join(tags, "")
+}
+
+// DisplayPath shows renames and copies as "old → new".
+func DisplayPath(f gitx.FileChange) string {
+	if f.OldPath == "" {
+		return f.Path
+	}
+	return f.OldPath + " → " + f.Path
+}
+
+// RenameHeader describes a rename or copy above its diff, or returns "".
+func RenameHeader(f gitx.FileChange) string {
+	var verb string
+	switch {
+	case f.Renamed:
+		verb = "renamed"
+	case f.Copied:
+		verb = "copied"
+	default:
+		return ""
+	}
+	return fmt.Sprintf("%s: %s (%d%% similar)", verb, DisplayPath(f), f.Similarity)
+}
+
+func hasHunks(rows []diffview.Row) bool {
+	for _, r := range rows {
+		if r.Kind == diffview.RowHunk {
+			return true
+		}
+	}
+	return false
+}
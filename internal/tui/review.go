@@ -0,0 +1,209 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/interpretive-systems/diffium/internal/diffview"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+	"github.com/interpretive-systems/diffium/internal/review"
+)
+
+// --- Review state: files and hunks marked reviewed (see package review) ---
+
+// reviewMsg carries the current hunk hashes of the files with review marks
+// in scope. When prune is set the hashes are the full working tree or staged
+// diffs, so marks missing from them are stale; a nil entry means the file is
+// unchanged.
+type reviewMsg struct {
+	scope string
+	hunks map[string][]string
+	prune bool
+	err   error
+}
+
+// openReview loads the review marks, dropping the scopes of ranges whose
+// commits are gone. Unreadable marks are reported and replaced by empty
+// ones.
+func (m *model) openReview() {
+	s, err := review.Load(m.repoRoot)
+	if err != nil {
+		m.status = "review marks unreadable, starting over: " + err.Error()
+	}
+	if s == nil {
+		return
+	}
+	if s.PruneRanges(m.repoRoot) {
+		_ = s.Save()
+	}
+	m.review, m.reviewed = s, map[string]bool{}
+}
+
+// reviewScope is the scope of the marks for what the panes compare.
+func (m model) reviewScope() string {
+	switch {
+	case m.inRange():
+		return review.RangeScope(m.diffRange.Base, m.diffRange.Head)
+	case m.diffMode == "staged":
+		return review.Index
+	}
+	return review.Worktree
+}
+
+// marks returns the review marks for what the panes compare.
+func (m model) marks() *review.Marks {
+	return m.review.Scope(m.reviewScope())
+}
+
+// loadReview hashes the hunks of every file that has review marks, so the
+// file list can show which are fully reviewed.
+func (m model) loadReview() tea.Cmd {
+	if m.review == nil || m.diffMode == "snapshot" {
+		return nil
+	}
+	scope, paths := m.reviewScope(), m.marks().Paths()
+	if len(paths) == 0 {
+		return func() tea.Msg { return reviewMsg{scope: scope} }
+	}
+	repoRoot, r, staged := m.repoRoot, m.diffRange, m.diffMode == "staged"
+	return func() tea.Msg {
+		var files []gitx.FileChange
+		var err error
+		if r.Base != "" {
+			files, err = gitx.RangeFiles(repoRoot, r)
+		} else {
+			files, err = gitx.ChangedFiles(repoRoot)
+		}
+		if err != nil {
+			return reviewMsg{err: err}
+		}
+		byPath := make(map[string]gitx.FileChange, len(files))
+		for _, f := range files {
+			byPath[f.Path] = f
+		}
+		msg := reviewMsg{scope: scope, hunks: map[string][]string{}, prune: r.Base == ""}
+		for _, p := range paths {
+			f, ok := byPath[p]
+			if !ok {
+				if msg.prune {
+					msg.hunks[p] = nil
+				}
+				continue
+			}
+			var d string
+			if r.Base != "" {
+				d, err = gitx.DiffRange(repoRoot, r, f)
+			} else {
+				d, err = gitx.DiffFile(repoRoot, f, staged)
+			}
+			if err != nil {
+				return reviewMsg{err: err}
+			}
+			msg.hunks[p] = review.Hashes(diffview.BuildRowsFromUnified(d))
+		}
+		return msg
+	}
+}
+
+func (m model) reviewResult(msg reviewMsg) (model, tea.Cmd) {
+	if msg.err != nil || m.review == nil {
+		return m, nil
+	}
+	marks := m.review.Scope(msg.scope)
+	changed := false
+	reviewed := map[string]bool{}
+	for p, hashes := range msg.hunks {
+		if msg.prune && marks.Retain(p, hashes) {
+			changed = true
+		}
+		reviewed[p] = marks.FileReviewed(p, hashes)
+	}
+	if changed {
+		_ = m.review.Save()
+	}
+	if msg.scope != m.reviewScope() {
+		return m, nil // the view changed meanwhile
+	}
+	m.reviewed = reviewed
+	return m, m.recalcViewport()
+}
+
+// toggleReviewed marks the hunk under the diff cursor reviewed, or the whole
+// selected file when hunk is false; if already reviewed it is unmarked.
+func (m *model) toggleReviewed(hunk bool) {
+	if m.review == nil || len(m.files) == 0 || m.conflictView() {
+		return
+	}
+	path := m.files[m.selected].Path
+	if m.rowsPath != path || m.rows == nil {
+		m.status = "diff not loaded yet"
+		return
+	}
+	what := path
+	hashes := review.Hashes(m.rows)
+	if hunk {
+		start, end, ok := diffview.HunkBounds(m.rows, m.diffCursor)
+		if !ok {
+			m.status = "no hunk under cursor"
+			return
+		}
+		hashes = []string{review.HunkHash(m.rows, start, end)}
+		what = "hunk"
+	}
+	if len(hashes) == 0 {
+		return
+	}
+	marks := m.marks()
+	if marks.FileReviewed(path, hashes) {
+		marks.Unmark(path, hashes...)
+		m.status = "unmarked " + what
+	} else {
+		marks.Mark(path, hashes...)
+		m.status = what + " reviewed"
+	}
+	if err := m.review.Save(); err != nil {
+		m.status = fmt.Sprintf("save review state: %v", err)
+	}
+	if m.reviewed == nil {
+		m.reviewed = map[string]bool{}
+	}
+	m.reviewed[path] = marks.FileReviewed(path, review.Hashes(m.rows))
+}
+
+// refreshReviewed updates the file list mark of the selected file after its
+// diff was reloaded.
+func (m *model) refreshReviewed() {
+	if m.review == nil || m.rows == nil || m.conflictView() || m.diffMode == "snapshot" {
+		return
+	}
+	marks := m.marks()
+	if !marks.Has(m.rowsPath) {
+		delete(m.reviewed, m.rowsPath)
+		return
+	}
+	// a range keeps its marks; the working tree and index drop stale ones
+	hashes := review.Hashes(m.rows)
+	if !m.inRange() && marks.Retain(m.rowsPath, hashes) {
+		_ = m.review.Save()
+	}
+	m.reviewed[m.rowsPath] = marks.FileReviewed(m.rowsPath, hashes)
+}
+
+// hunkReviewed reports whether the hunk starting at row start is marked.
+func (m model) hunkReviewed(start int) bool {
+	if m.review == nil {
+		return false
+	}
+	_, end, ok := diffview.HunkBounds(m.rows, start)
+	return ok && m.marks().Reviewed(m.rowsPath, review.HunkHash(m.rows, start, end))
+}
+
+// hunkSeparator renders the rule above the hunk at row i, led by a check
+// mark once the hunk is reviewed.
+func (m model) hunkSeparator(i, width int) string {
+	if !m.hunkReviewed(i) || width < 2 {
+		return hunkRule(width)
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("34")).Render("✓") + hunkRule(width-1)
+}
@@ -0,0 +1,49 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+)
+
+// --- Snapshots: diff the working tree against a recorded state ---
+
+type snapshotMsg struct {
+	s   gitx.Snapshot
+	err error
+}
+
+// loadSnapshotFiles lists the files changed since the latest snapshot. It
+// never takes one: without a snapshot the list is empty until S is pressed.
+func loadSnapshotFiles(repoRoot string) tea.Cmd {
+	return func() tea.Msg {
+		r, ok, err := gitx.SinceSnapshot(repoRoot)
+		if err != nil {
+			return filesMsg{err: err}
+		}
+		if !ok {
+			return filesMsg{noSnapshot: true}
+		}
+		files, err := gitx.RangeFiles(repoRoot, r)
+		return filesMsg{files: files, snapshot: r, err: err}
+	}
+}
+
+func takeSnapshot(repoRoot string) tea.Cmd {
+	return func() tea.Msg {
+		s, err := gitx.TakeSnapshot(repoRoot)
+		return snapshotMsg{s: s, err: err}
+	}
+}
+
+func (m model) snapshotResult(msg snapshotMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		m.status = "snapshot: " + msg.err.Error()
+		return m, nil
+	}
+	m.status = "snapshot taken at " + msg.s.Time.Format("15:04:05")
+	if m.diffMode != "snapshot" || m.inRange() {
+		m.status += " (t: show changes since)"
+		return m, nil
+	}
+	return m, tea.Batch(m.reloadFiles(), m.recalcViewport())
+}
@@ -0,0 +1,424 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/interpretive-systems/diffium/internal/diffview"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+)
+
+// --- Diff pane cursor, hunk and line staging ---
+
+type patchResultMsg struct {
+	done string // past-tense description for the status line
+	err  error
+}
+
+// focusDiff moves keyboard focus to the diff pane, placing the cursor on the
+// first changed row visible in the viewport.
+func (m *model) focusDiff() {
+	if m.conflictView() {
+		m.diffFocus = true
+		return
+	}
+	if len(m.rows) == 0 {
+		return
+	}
+	m.diffFocus = true
+	if i, ok := m.firstVisibleChange(); ok {
+		m.diffCursor = i
+		return
+	}
+	m.clampDiffCursor()
+}
+
+// firstVisibleChange returns the first changed row at or below the top of
+// the diff pane.
+func (m model) firstVisibleChange() (int, bool) {
+	top := m.rightVP.YOffset
+	for i := range m.rows {
+		if i < len(m.rowStarts) && m.rowStarts[i] >= top && m.rows[i].IsChange() {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// clampDiffCursor keeps the cursor on a content row after rows change.
+func (m *model) clampDiffCursor() {
+	if len(m.rows) == 0 {
+		m.diffCursor = 0
+		return
+	}
+	if m.diffCursor >= len(m.rows) {
+		m.diffCursor = len(m.rows) - 1
+	}
+	if m.diffCursor < 0 {
+		m.diffCursor = 0
+	}
+	if isCursorRow(m.rows[m.diffCursor]) {
+		return
+	}
+	if !m.moveDiffCursor(1) {
+		m.moveDiffCursor(-1)
+	}
+}
+
+func isCursorRow(r diffview.Row) bool {
+	return r.Kind != diffview.RowHunk && r.Kind != diffview.RowMeta
+}
+
+// moveDiffCursor moves to the next content row in direction delta (+1/-1).
+func (m *model) moveDiffCursor(delta int) bool {
+	for i := m.diffCursor + delta; i >= 0 && i < len(m.rows); i += delta {
+		if isCursorRow(m.rows[i]) {
+			m.diffCursor = i
+			return true
+		}
+	}
+	return false
+}
+
+// jumpHunk moves the cursor to the first changed row of the next/previous hunk.
+func (m *model) jumpHunk(delta int) {
+	start, _, ok := diffview.HunkBounds(m.rows, m.diffCursor)
+	if !ok {
+		return
+	}
+	for i := start + delta; i >= 0 && i < len(m.rows); i += delta {
+		if m.rows[i].Kind != diffview.RowHunk {
+			continue
+		}
+		_, end, _ := diffview.HunkBounds(m.rows, i)
+		for j := i + 1; j < end; j++ {
+			if m.rows[j].IsChange() {
+				m.diffCursor = j
+				return
+			}
+		}
+	}
+}
+
+// ensureCursorVisible scrolls the diff viewport so the cursor row is shown.
+func (m *model) ensureCursorVisible() {
+	if m.diffCursor < 0 || m.diffCursor >= len(m.rowStarts) {
+		return
+	}
+	line := m.rowStarts[m.diffCursor]
+	h := m.rightVP.Height
+	if h <= 0 {
+		return
+	}
+	if line < m.rightVP.YOffset {
+		m.rightVP.SetYOffset(line)
+	} else if line >= m.rightVP.YOffset+h {
+		m.rightVP.SetYOffset(line - h + 1)
+	}
+}
+
+// handleDiffKeys handles keys while the diff pane has focus. Keys it does not
+// handle fall through to the normal bindings.
+func (m model) handleDiffKeys(key tea.KeyMsg) (model, tea.Cmd, bool) {
+	if what := m.readOnly(); what != "" {
+		switch key.String() {
+		case " ", "v", "d":
+			m.status = "read-only while reviewing " + what
+			return m, nil, true
+		}
+	}
+	switch key.String() {
+	case "tab":
+		m.diffFocus = false
+		m.visualActive = false
+		return m, m.recalcViewport(), true
+	case "esc":
+		if m.visualActive {
+			m.visualActive = false
+		} else {
+			m.diffFocus = false
+		}
+		return m, m.recalcViewport(), true
+	case "v":
+		m.visualActive = !m.visualActive
+		m.visualAnchor = m.diffCursor
+	case "j", "down":
+		m.moveDiffCursor(1)
+	case "k", "up":
+		m.moveDiffCursor(-1)
+	case "g":
+		m.diffCursor = 0
+		m.clampDiffCursor()
+	case "G":
+		m.diffCursor = len(m.rows) - 1
+		m.clampDiffCursor()
+	case "]":
+		m.jumpHunk(1)
+	case "[":
+		m.jumpHunk(-1)
+	case "d":
+		m.openDiscard()
+		return m, m.recalcViewport(), true
+	case "m":
+		m.toggleFinding()
+		return m, m.recalcViewport(), true
+	case "x":
+		m.toggleReviewed(true)
+		return m, m.recalcViewport(), true
+	case " ":
+		if m.visualActive {
+			from, to := m.selectionRange()
+			m.visualActive = false
+			return m, m.applyRows(from, to, "lines"), true
+		}
+		return m, m.stageHunk(), true
+	default:
+		return m, nil, false
+	}
+	m.recalcViewport()
+	m.ensureCursorVisible()
+	return m, nil, true
+}
+
+// stageHunk stages (HEAD mode) or unstages (staged mode) the hunk under the cursor.
+func (m *model) stageHunk() tea.Cmd {
+	start, end, ok := diffview.HunkBounds(m.rows, m.diffCursor)
+	if !ok {
+		m.status = "no hunk under cursor"
+		return nil
+	}
+	return m.applyRows(start, end, "hunk")
+}
+
+// selectionRange returns the row range [from, to) covered by the visual selection.
+func (m model) selectionRange() (int, int) {
+	from, to := m.visualAnchor, m.diffCursor
+	if from > to {
+		from, to = to, from
+	}
+	return from, to + 1
+}
+
+// applyRows builds a patch from rows[from:to] and stages or unstages it
+// depending on the diff mode.
+func (m *model) applyRows(from, to int, what string) tea.Cmd {
+	if len(m.files) == 0 {
+		return nil
+	}
+	reverse := m.diffMode == "staged"
+	patch, err := diffview.BuildPatch(m.rows, from, to, reverse)
+	if err != nil {
+		m.status = err.Error()
+		return nil
+	}
+	// Staging part of a move that only exists in the working tree carries the
+	// rename into the index; otherwise the new path is already there.
+	if f := m.files[m.selected]; f.OldPath != "" && (reverse || !f.Untracked) {
+		patch = diffview.WithoutRename(patch)
+	}
+	repoRoot := m.repoRoot
+	if reverse {
+		return func() tea.Msg {
+			return patchResultMsg{done: "unstaged " + what, err: gitx.UnstagePatch(repoRoot, patch)}
+		}
+	}
+	f := m.files[m.selected]
+	if f.Untracked {
+		return func() tea.Msg {
+			return patchResultMsg{done: "staged " + what, err: gitx.StagePatch(repoRoot, patch)}
+		}
+	}
+	// The rows compare HEAD with the working tree, but the patch is applied
+	// to the index, which may already hold some of the changes: rebuild it
+	// from the unstaged diff.
+	rows := m.rows
+	return func() tea.Msg {
+		d, err := gitx.DiffUnstaged(repoRoot, f.Path)
+		if err != nil {
+			return patchResultMsg{err: err}
+		}
+		unstaged := diffview.BuildRowsFromUnified(d)
+		ufrom, uto, ok := diffview.MapSelection(rows, from, to, unstaged)
+		if !ok {
+			return patchResultMsg{err: fmt.Errorf("%s is already staged", what)}
+		}
+		patch, err := diffview.BuildPatch(unstaged, ufrom, uto, false)
+		if err != nil {
+			return patchResultMsg{err: err}
+		}
+		return patchResultMsg{done: "staged " + what, err: gitx.StagePatch(repoRoot, patch)}
+	}
+}
+
+// addDiffGutter prefixes every rendered line with a one-column gutter that
+// marks the cursor row and any visually selected rows.
+func (m model) addDiffGutter(lines []string, starts []int) []string {
+	cursor := lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Render("▌")
+	selected := lipgloss.NewStyle().Foreground(lipgloss.Color("220")).Render("▌")
+	marked := lipgloss.NewStyle().Foreground(lipgloss.Color("170")).Render("•")
+	selFrom, selTo := -1, -1
+	if m.visualActive {
+		selFrom, selTo = m.selectionRange()
+	}
+	out := make([]string, len(lines))
+	row := 0
+	for l, line := range lines {
+		for row+1 < len(starts) && starts[row+1] <= l {
+			row++
+		}
+		mark := " "
+		switch {
+		case len(starts) > 0 && l < starts[0]:
+			// lines above the first row, e.g. a rename notice
+		case row == m.diffCursor:
+			mark = cursor
+		case row >= selFrom && row < selTo:
+			mark = selected
+		case l == starts[row] && m.findingAt(row) >= 0:
+			mark = marked
+		}
+		out[l] = mark + line
+	}
+	return out
+}
+
+func (m model) patchResult(msg patchResultMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		m.status = strings.ReplaceAll(strings.TrimSpace(msg.err.Error()), "\n", " ")
+	} else {
+		m.status = msg.done
+	}
+	return m, tea.Batch(m.reloadFiles(), loadCurrentDiff(m), m.recalcViewport())
+}
+
+// --- Discard hunk/selection wizard ---
+
+type discardResultMsg struct{ err error }
+
+// openDiscard prepares a reverse patch for the hunk under the cursor, or the
+// visual selection if active, and opens the two-step confirmation.
+func (m *model) openDiscard() {
+	if len(m.files) == 0 {
+		return
+	}
+	// the patch is applied to the working tree, which the staged diff is not
+	if m.diffMode == "staged" {
+		m.status = "cannot discard staged changes; unstage them first"
+		return
+	}
+	from, to, what := 0, 0, "hunk"
+	if m.visualActive {
+		from, to = m.selectionRange()
+		what = "lines"
+	} else {
+		start, end, ok := diffview.HunkBounds(m.rows, m.diffCursor)
+		if !ok {
+			m.status = "no hunk under cursor"
+			return
+		}
+		from, to = start, end
+	}
+	patch, err := diffview.BuildPatch(m.rows, from, to, true)
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	if m.files[m.selected].OldPath != "" {
+		patch = diffview.WithoutRename(patch)
+	}
+	m.showDiscard = true
+	m.dcStep = 0
+	m.dcPatch = patch
+	m.dcWhat = what
+	m.dcPath = m.files[m.selected].Path
+	m.dcRunning = false
+	m.dcErr = ""
+}
+
+func (m model) discardOverlayLines(width int) []string {
+	if !m.showDiscard {
+		return nil
+	}
+	lines := make([]string, 0, 16)
+	lines = append(lines, strings.Repeat("─", width))
+	switch m.dcStep {
+	case 0: // first (yellow) confirmation with a preview
+		title := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("220")).Render("Discard " + m.dcWhat + " — Working tree changes will be lost (enter: continue, esc: cancel)")
+		lines = append(lines, title)
+		lines = append(lines, "File: "+m.dcPath)
+		var changed []string
+		for _, l := range strings.Split(m.dcPatch, "\n") {
+			if strings.HasPrefix(l, "+++ ") || strings.HasPrefix(l, "--- ") {
+				continue
+			}
+			if strings.HasPrefix(l, "+") {
+				changed = append(changed, m.theme.AddText(l))
+			} else if strings.HasPrefix(l, "-") {
+				changed = append(changed, m.theme.DelText(l))
+			}
+		}
+		max := 8
+		for i, l := range changed {
+			if i >= max {
+				lines = append(lines, fmt.Sprintf("… and %d more", len(changed)-max))
+				break
+			}
+			lines = append(lines, l)
+		}
+	case 1: // final (red) confirmation
+		title := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("196")).Render("FINAL CONFIRMATION — Discard " + m.dcWhat + " (y/enter: execute, b: back, esc: cancel)")
+		lines = append(lines, title)
+		if m.dcRunning {
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Render("Discarding…"))
+		}
+		if m.dcErr != "" {
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("Error: ")+m.dcErr)
+		}
+	}
+	return lines
+}
+
+func (m model) handleDiscardKeys(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch m.dcStep {
+	case 0:
+		switch key.String() {
+		case "esc":
+			m.showDiscard = false
+			return m, m.recalcViewport()
+		case "enter":
+			m.dcStep = 1
+			return m, m.recalcViewport()
+		}
+	case 1:
+		switch key.String() {
+		case "esc":
+			if !m.dcRunning {
+				m.showDiscard = false
+				return m, m.recalcViewport()
+			}
+			return m, nil
+		case "b":
+			if !m.dcRunning {
+				m.dcStep = 0
+				return m, m.recalcViewport()
+			}
+			return m, nil
+		case "y", "enter":
+			if !m.dcRunning {
+				m.dcRunning = true
+				m.dcErr = ""
+				return m, runDiscard(m.repoRoot, m.dcPatch)
+			}
+			return m, nil
+		}
+	}
+	return m, nil
+}
+
+func runDiscard(repoRoot, patch string) tea.Cmd {
+	return func() tea.Msg {
+		return discardResultMsg{err: gitx.DiscardPatch(repoRoot, patch)}
+	}
+}
@@ -0,0 +1,427 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+)
+
+// --- Stash wizard: list stashes (diff in the panes), create, apply, pop, drop ---
+
+const stashVisible = 10 // rows shown at once
+
+type stashListMsg struct {
+	list []gitx.StashEntry
+	err  error
+}
+
+type stashRangeMsg struct {
+	hash string
+	r    gitx.Range
+	err  error
+}
+
+type stashFilesMsg struct {
+	files []gitx.FileChange
+	err   error
+}
+
+type stashResultMsg struct {
+	done  string
+	close bool // leave the wizard, e.g. after applying
+	err   error
+}
+
+func loadStashes(repoRoot string) tea.Cmd {
+	return func() tea.Msg {
+		list, err := gitx.ListStashes(repoRoot)
+		return stashListMsg{list: list, err: err}
+	}
+}
+
+func loadStashRange(repoRoot string, s gitx.StashEntry) tea.Cmd {
+	return func() tea.Msg {
+		r, err := s.Range(repoRoot)
+		r.Label = s.Ref + " " + s.Subject
+		return stashRangeMsg{hash: s.Hash, r: r, err: err}
+	}
+}
+
+func loadStashFiles(repoRoot string) tea.Cmd {
+	return func() tea.Msg {
+		files, err := gitx.ChangedFiles(repoRoot)
+		return stashFilesMsg{files: files, err: err}
+	}
+}
+
+func (m *model) openStashWizard() {
+	m.showStash = true
+	m.stStep = 0
+	m.stList = nil
+	m.stIndex = 0
+	m.stOffset = 0
+	m.stErr = ""
+	m.stRunning = false
+	m.stPrevRange = m.diffRange
+}
+
+// closeStashWizard puts back what the panes showed before the wizard.
+func (m *model) closeStashWizard() tea.Cmd {
+	m.showStash = false
+	if m.diffRange != m.stPrevRange {
+		return m.previewRange(m.stPrevRange)
+	}
+	return m.recalcViewport()
+}
+
+// previewStash shows the stash under the cursor in the main panes.
+func (m model) previewStash() tea.Cmd {
+	if m.stIndex >= len(m.stList) {
+		return nil
+	}
+	return loadStashRange(m.repoRoot, m.stList[m.stIndex])
+}
+
+func (m model) stashListResult(msg stashListMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		m.stErr = msg.err.Error()
+		return m, m.recalcViewport()
+	}
+	m.stList = msg.list
+	if m.stList == nil {
+		m.stList = []gitx.StashEntry{}
+	}
+	if m.stIndex >= len(m.stList) {
+		m.stIndex, m.stOffset = 0, 0
+	}
+	if len(m.stList) == 0 && m.diffRange != m.stPrevRange {
+		return m, m.previewRange(m.stPrevRange)
+	}
+	return m, tea.Batch(m.previewStash(), m.recalcViewport())
+}
+
+func (m model) stashRangeResult(msg stashRangeMsg) (model, tea.Cmd) {
+	if !m.showStash || m.stStep > 2 || m.stIndex >= len(m.stList) || m.stList[m.stIndex].Hash != msg.hash {
+		return m, nil // the cursor moved on
+	}
+	if msg.err != nil {
+		m.stErr = msg.err.Error()
+		return m, m.recalcViewport()
+	}
+	return m, m.previewRange(msg.r)
+}
+
+func (m model) stashFilesResult(msg stashFilesMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		m.stErr = msg.err.Error()
+		return m, m.recalcViewport()
+	}
+	m.stFiles = msg.files
+	m.stSelected = map[string]bool{}
+	for _, f := range m.stFiles {
+		m.stSelected[f.Path] = !f.Untracked || m.stUntracked
+	}
+	m.stFileIndex = 0
+	return m, m.recalcViewport()
+}
+
+func (m model) stashResult(msg stashResultMsg) (model, tea.Cmd) {
+	m.stRunning = false
+	if msg.err != nil {
+		m.stErr = strings.ReplaceAll(strings.TrimSpace(msg.err.Error()), "\n", " ")
+		return m, tea.Batch(m.reloadFiles(), m.recalcViewport())
+	}
+	m.stErr = ""
+	m.status = msg.done
+	if msg.close {
+		return m, m.closeStashWizard()
+	}
+	m.stStep = 0
+	m.stIndex, m.stOffset = 0, 0
+	return m, tea.Batch(loadStashes(m.repoRoot), m.recalcViewport())
+}
+
+// stashPaths returns the paths to stash, or nil for everything.
+func (m model) stashPaths() (paths []string, all bool) {
+	all = true
+	for _, f := range m.stFiles {
+		if f.Untracked && !m.stUntracked {
+			continue
+		}
+		if m.stSelected[f.Path] {
+			paths = append(paths, f.Path)
+		} else {
+			all = false
+		}
+	}
+	if all {
+		return nil, true
+	}
+	return paths, false
+}
+
+func runStash(repoRoot, verb string, s gitx.StashEntry) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		switch verb {
+		case "apply":
+			err = gitx.StashApply(repoRoot, s)
+		case "pop":
+			err = gitx.StashPop(repoRoot, s)
+		case "drop":
+			err = gitx.StashDrop(repoRoot, s)
+		}
+		past := map[string]string{"apply": "applied", "pop": "popped", "drop": "dropped"}[verb]
+		return stashResultMsg{done: past + " " + s.Ref, close: verb != "drop", err: err}
+	}
+}
+
+func runStashPush(repoRoot, message string, paths []string, untracked bool) tea.Cmd {
+	return func() tea.Msg {
+		err := gitx.StashPush(repoRoot, message, paths, untracked)
+		return stashResultMsg{done: "stashed changes", err: err}
+	}
+}
+
+func (m model) handleStashKeys(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.stRunning {
+		return m, nil
+	}
+	switch m.stStep {
+	case 0: // list
+		switch key.String() {
+		case "esc", "q":
+			return m, m.closeStashWizard()
+		case "j", "down":
+			if m.stIndex < len(m.stList)-1 {
+				m.stIndex++
+			}
+		case "k", "up":
+			if m.stIndex > 0 {
+				m.stIndex--
+			}
+		case "g":
+			m.stIndex = 0
+		case "G":
+			m.stIndex = max(len(m.stList)-1, 0)
+		case "a", "p", "d":
+			if len(m.stList) == 0 {
+				return m, nil
+			}
+			m.stVerb = map[string]string{"a": "apply", "p": "pop", "d": "drop"}[key.String()]
+			m.stStep = 1
+			m.stErr = ""
+			return m, m.recalcViewport()
+		case "n":
+			m.stStep = 3
+			m.stFiles = nil
+			m.stErr = ""
+			return m, tea.Batch(loadStashFiles(m.repoRoot), m.recalcViewport())
+		default:
+			return m, nil
+		}
+		if m.stIndex < m.stOffset {
+			m.stOffset = m.stIndex
+		} else if m.stIndex >= m.stOffset+stashVisible {
+			m.stOffset = m.stIndex - stashVisible + 1
+		}
+		return m, m.previewStash()
+	case 1, 2: // confirm; a drop asks twice
+		switch key.String() {
+		case "esc":
+			return m, m.closeStashWizard()
+		case "b":
+			m.stStep--
+			m.stErr = ""
+			return m, m.recalcViewport()
+		case "y", "enter":
+			if m.stVerb == "drop" && m.stStep == 1 {
+				if key.String() == "enter" {
+					m.stStep = 2
+					return m, m.recalcViewport()
+				}
+				return m, nil
+			}
+			m.stRunning = true
+			m.stErr = ""
+			return m, runStash(m.repoRoot, m.stVerb, m.stList[m.stIndex])
+		}
+	case 3: // select files
+		switch key.String() {
+		case "esc":
+			return m, m.closeStashWizard()
+		case "b":
+			m.stStep = 0
+			m.stErr = ""
+			return m, tea.Batch(m.previewStash(), m.recalcViewport())
+		case "j", "down":
+			if m.stFileIndex < len(m.stFiles)-1 {
+				m.stFileIndex++
+			}
+		case "k", "up":
+			if m.stFileIndex > 0 {
+				m.stFileIndex--
+			}
+		case " ":
+			if m.stFileIndex < len(m.stFiles) {
+				f := m.stFiles[m.stFileIndex]
+				if !f.Untracked || m.stUntracked {
+					m.stSelected[f.Path] = !m.stSelected[f.Path]
+				}
+			}
+		case "a":
+			_, all := m.stashPaths()
+			for _, f := range m.stFiles {
+				if !f.Untracked || m.stUntracked {
+					m.stSelected[f.Path] = !all
+				}
+			}
+		case "u":
+			m.stUntracked = !m.stUntracked
+			for _, f := range m.stFiles {
+				if f.Untracked {
+					m.stSelected[f.Path] = m.stUntracked
+				}
+			}
+		case "enter":
+			if paths, all := m.stashPaths(); !all && len(paths) == 0 {
+				m.stErr = "no files selected"
+				return m, nil
+			}
+			ti := textinput.New()
+			ti.Placeholder = "Stash message (optional)"
+			ti.Prompt = "> "
+			ti.Focus()
+			m.stInput = ti
+			m.stStep = 4
+			m.stErr = ""
+			return m, m.recalcViewport()
+		}
+		return m, nil
+	case 4: // message
+		switch key.String() {
+		case "esc":
+			m.stStep = 3
+			return m, m.recalcViewport()
+		case "enter":
+			paths, _ := m.stashPaths()
+			m.stRunning = true
+			m.stErr = ""
+			return m, runStashPush(m.repoRoot, strings.TrimSpace(m.stInput.Value()), paths, m.stUntracked)
+		}
+		var cmd tea.Cmd
+		m.stInput, cmd = m.stInput.Update(key)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m model) stashOverlayLines(width int) []string {
+	if !m.showStash {
+		return nil
+	}
+	lines := make([]string, 0, stashVisible+6)
+	lines = append(lines, strings.Repeat("─", width))
+	faint := lipgloss.NewStyle().Faint(true)
+	switch m.stStep {
+	case 0:
+		title := lipgloss.NewStyle().Bold(true).Render("Stashes — Select (a: apply, p: pop, d: drop, n: new stash, esc: close)")
+		lines = append(lines, title)
+		if m.stList == nil && m.stErr == "" {
+			lines = append(lines, faint.Render("Loading stashes…"))
+			break
+		}
+		if len(m.stList) == 0 && m.stErr == "" {
+			lines = append(lines, faint.Render("No stashes (n: stash the current changes)"))
+			break
+		}
+		hash := lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
+		end := min(m.stOffset+stashVisible, len(m.stList))
+		for i := m.stOffset; i < end; i++ {
+			s := m.stList[i]
+			cur := "  "
+			if i == m.stIndex {
+				cur = "> "
+			}
+			lines = append(lines, fmt.Sprintf("%s%s %s  %s", cur, hash.Render(s.Ref), faint.Render(s.Date.Format("2006-01-02 15:04")), s.Subject))
+		}
+		if m.stRunning {
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Render("Running…"))
+		}
+	case 1: // first (yellow) confirmation
+		var title, note string
+		switch m.stVerb {
+		case "apply":
+			title = "Apply stash — Confirm (y/enter: apply, b: back, esc: cancel)"
+			note = "The stash is applied to the working tree and kept."
+		case "pop":
+			title = "Pop stash — Confirm (y/enter: pop, b: back, esc: cancel)"
+			note = "The stash is applied to the working tree and dropped, unless the apply conflicts."
+		default:
+			title = "Drop stash — Confirm (enter: continue, b: back, esc: cancel)"
+			note = "A dropped stash can only be recovered by its hash: " + m.stList[m.stIndex].Hash
+		}
+		lines = append(lines, lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("220")).Render(title))
+		s := m.stList[m.stIndex]
+		lines = append(lines, fmt.Sprintf("%s  %s", s.Ref, s.Subject))
+		lines = append(lines, faint.Render(note))
+		if m.stRunning {
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Render("Running…"))
+		}
+	case 2: // final (red) confirmation of a drop
+		title := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("196")).Render("FINAL CONFIRMATION — Drop stash (y/enter: drop, b: back, esc: cancel)")
+		lines = append(lines, title)
+		s := m.stList[m.stIndex]
+		lines = append(lines, fmt.Sprintf("%s  %s", s.Ref, s.Subject))
+		if m.stRunning {
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Render("Dropping…"))
+		}
+	case 3:
+		title := lipgloss.NewStyle().Bold(true).Render("New stash — Select files (space: toggle, a: all, u: untracked, enter: continue, b: back, esc: cancel)")
+		lines = append(lines, title)
+		if m.stFiles == nil && m.stErr == "" {
+			lines = append(lines, faint.Render("Loading changes…"))
+			break
+		}
+		if len(m.stFiles) == 0 && m.stErr == "" {
+			lines = append(lines, faint.Render("No changes to stash"))
+			break
+		}
+		for i, f := range m.stFiles {
+			cur := "  "
+			if i == m.stFileIndex {
+				cur = "> "
+			}
+			line := fmt.Sprintf("%s%s %s %s", cur, checkbox(m.stSelected[f.Path]), StatusLabel(f), DisplayPath(f))
+			if f.Untracked && !m.stUntracked {
+				line = faint.Render(line)
+			}
+			lines = append(lines, line)
+		}
+		lines = append(lines, faint.Render("Include untracked files: "+checkbox(m.stUntracked)))
+	case 4:
+		title := lipgloss.NewStyle().Bold(true).Render("New stash — Message (enter: stash, esc: back)")
+		lines = append(lines, title)
+		lines = append(lines, m.stInput.View())
+		paths, all := m.stashPaths()
+		what := "all changes"
+		if !all {
+			what = fmt.Sprintf("%d files", len(paths))
+		}
+		if m.stUntracked {
+			what += ", untracked included"
+		}
+		lines = append(lines, faint.Render("Stashing "+what))
+		if m.stRunning {
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Render("Stashing…"))
+		}
+	}
+	if m.stErr != "" {
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("Error: ")+m.stErr)
+	}
+	return lines
+}
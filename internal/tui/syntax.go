@@ -0,0 +1,101 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+	"github.com/interpretive-systems/diffium/internal/syntax"
+)
+
+// --- Syntax highlighting of the diff pane ---
+
+// maxHighlightBytes skips highlighting for very large files.
+const maxHighlightBytes = 1 << 20
+
+type syntaxMsg struct {
+	path     string
+	old, new [][]syntax.Span // per line of each file version
+}
+
+// loadSyntax highlights the old and new versions of the selected file. The
+// whole files are tokenized so that strings and comments spanning lines are
+// colored correctly in every hunk.
+func (m model) loadSyntax() tea.Cmd {
+	if m.theme.NoSyntax || len(m.files) == 0 {
+		return nil
+	}
+	f := m.files[m.selected]
+	if f.Binary || f.Submodule || (f.Conflicted && !m.inRange() && m.diffMode != "staged") {
+		return nil
+	}
+	// rev "" with fromIndex false means the working tree
+	oldRev, newRev, fromIndex := "HEAD", "", false
+	switch {
+	case m.inRange():
+		oldRev, newRev = m.diffRange.Base, m.diffRange.Head
+	case m.diffMode == "snapshot":
+		oldRev, newRev = m.snapRange.Base, m.snapRange.Head
+	case m.diffMode == "staged":
+		fromIndex = true
+	}
+	repoRoot := m.repoRoot
+	return func() tea.Msg {
+		msg := syntaxMsg{path: f.Path}
+		msg.old, msg.new = highlight(repoRoot, f, oldRev, newRev, fromIndex)
+		return msg
+	}
+}
+
+// HighlightChange highlights the old and new versions of f: HEAD against
+// the index when staged is set, against the working tree otherwise.
+func HighlightChange(repoRoot string, f gitx.FileChange, staged bool) (old, new [][]syntax.Span) {
+	if f.Binary || f.Submodule || (f.Conflicted && !staged) {
+		return nil, nil
+	}
+	return highlight(repoRoot, f, "HEAD", "", staged)
+}
+
+// highlight tokenizes f at oldRev and newRev, where newRev "" is the index
+// when fromIndex is set and the working tree otherwise.
+func highlight(repoRoot string, f gitx.FileChange, oldRev, newRev string, fromIndex bool) (old, new [][]syntax.Span) {
+	oldPath := f.Path
+	if f.OldPath != "" {
+		oldPath = f.OldPath
+	}
+	var oldSrc, newSrc string
+	if !f.Untracked && !f.Added {
+		oldSrc, _ = gitx.ShowFile(repoRoot, oldRev, oldPath)
+	}
+	if !f.Deleted {
+		if newRev != "" || fromIndex {
+			newSrc, _ = gitx.ShowFile(repoRoot, newRev, f.Path)
+		} else if b, err := os.ReadFile(filepath.Join(repoRoot, f.Path)); err == nil {
+			newSrc = string(b)
+		}
+	}
+	src := newSrc
+	if src == "" {
+		src = oldSrc
+	}
+	lang := syntax.Detect(f.Path, firstLine(src))
+	if lang == nil {
+		return nil, nil
+	}
+	if len(oldSrc) <= maxHighlightBytes {
+		old = syntax.Highlight(lang, oldSrc)
+	}
+	if len(newSrc) <= maxHighlightBytes {
+		new = syntax.Highlight(lang, newSrc)
+	}
+	return old, new
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/interpretive-systems/diffium/internal/diffview"
+	"github.com/interpretive-systems/diffium/internal/syntax"
 )
 
 // Theme defines customizable colors for rendering.
@@ -14,6 +18,43 @@ type Theme struct {
 	DelColor     string `json:"delColor"`     // e.g. "196" or "#ef4444"
 	MetaColor    string `json:"metaColor"`    // optional, currently unused
 	DividerColor string `json:"dividerColor"` // e.g. "240"
+	// Backgrounds for the changed parts of a replaced line
+	AddEmphColor string `json:"addEmphColor"` // e.g. "22" or "#14532d"
+	DelEmphColor string `json:"delEmphColor"` // e.g. "52" or "#7f1d1d"
+	// Backgrounds for whole added/deleted lines, under the syntax colors.
+	// "none" turns one off; added/deleted lines are then not highlighted,
+	// since the add/del color is all that marks them.
+	AddBgColor string `json:"addBgColor"`
+	DelBgColor string `json:"delBgColor"`
+	// Syntax highlighting palette; NoSyntax turns highlighting off
+	Syntax   SyntaxColors `json:"syntax"`
+	NoSyntax bool         `json:"noSyntax"`
+}
+
+// SyntaxColors are the foreground colors of highlighted tokens. An empty
+// color leaves that kind of token in the line's add/del/plain color.
+type SyntaxColors struct {
+	Keyword string `json:"keyword"`
+	Type    string `json:"type"`
+	String  string `json:"string"`
+	Comment string `json:"comment"`
+	Number  string `json:"number"`
+}
+
+func (c SyntaxColors) color(k syntax.Kind) string {
+	switch k {
+	case syntax.Keyword:
+		return c.Keyword
+	case syntax.Type:
+		return c.Type
+	case syntax.String:
+		return c.String
+	case syntax.Comment:
+		return c.Comment
+	case syntax.Number:
+		return c.Number
+	}
+	return ""
 }
 
 func defaultTheme() Theme {
@@ -22,11 +63,23 @@ func defaultTheme() Theme {
 		DelColor:     "196",
 		MetaColor:    "63",
 		DividerColor: "240",
+		AddEmphColor: "22",
+		DelEmphColor: "52",
+		AddBgColor:   "#12261a",
+		DelBgColor:   "#2b1417",
+		Syntax: SyntaxColors{
+			Keyword: "141",
+			Type:    "75",
+			String:  "179",
+			Comment: "245",
+			Number:  "173",
+		},
 	}
 }
 
-// loadThemeFromRepo tries .diffium/theme.json at repoRoot.
-func loadThemeFromRepo(repoRoot string) Theme {
+// LoadTheme returns the default theme merged with .diffium/theme.json at
+// repoRoot, when there is one.
+func LoadTheme(repoRoot string) Theme {
 	t := defaultTheme()
 	path := filepath.Join(repoRoot, ".diffium", "theme.json")
 	b, err := os.ReadFile(path)
@@ -50,6 +103,40 @@ func loadThemeFromRepo(repoRoot string) Theme {
 	if u.DividerColor != "" {
 		t.DividerColor = u.DividerColor
 	}
+	if u.AddEmphColor != "" {
+		t.AddEmphColor = u.AddEmphColor
+	}
+	if u.DelEmphColor != "" {
+		t.DelEmphColor = u.DelEmphColor
+	}
+	if u.AddBgColor != "" {
+		t.AddBgColor = u.AddBgColor
+	}
+	if u.DelBgColor != "" {
+		t.DelBgColor = u.DelBgColor
+	}
+	if t.AddBgColor == "none" {
+		t.AddBgColor = ""
+	}
+	if t.DelBgColor == "none" {
+		t.DelBgColor = ""
+	}
+	if u.Syntax.Keyword != "" {
+		t.Syntax.Keyword = u.Syntax.Keyword
+	}
+	if u.Syntax.Type != "" {
+		t.Syntax.Type = u.Syntax.Type
+	}
+	if u.Syntax.String != "" {
+		t.Syntax.String = u.Syntax.String
+	}
+	if u.Syntax.Comment != "" {
+		t.Syntax.Comment = u.Syntax.Comment
+	}
+	if u.Syntax.Number != "" {
+		t.Syntax.Number = u.Syntax.Number
+	}
+	t.NoSyntax = u.NoSyntax
 	return t
 }
 
@@ -64,3 +151,103 @@ func (t Theme) DelText(s string) string {
 func (t Theme) DividerText(s string) string {
 	return lipgloss.NewStyle().Foreground(lipgloss.Color(t.DividerColor)).Render(s)
 }
+
+// AddTextSpans renders an added line, putting the changed spans on the
+// emphasis background and coloring syntax tokens.
+func (t Theme) AddTextSpans(s string, spans []diffview.Span, syn []syntax.Span) string {
+	return t.renderCode(s, syn, spans, t.AddColor, t.AddBgColor, t.AddEmphColor)
+}
+
+// DelTextSpans renders a deleted line, putting the changed spans on the
+// emphasis background and coloring syntax tokens.
+func (t Theme) DelTextSpans(s string, spans []diffview.Span, syn []syntax.Span) string {
+	return t.renderCode(s, syn, spans, t.DelColor, t.DelBgColor, t.DelEmphColor)
+}
+
+// CodeText renders an unchanged line with syntax colors only.
+func (t Theme) CodeText(s string, syn []syntax.Span) string {
+	return t.renderCode(s, syn, nil, "", "", "")
+}
+
+// renderCode styles s in segments: syntax tokens take their palette color
+// over fg when there is no fg or a bg marks the line, and the emphasized
+// spans take emphBg over bg. Spans that do not fit s (a stale highlight, say)
+// are ignored.
+func (t Theme) renderCode(s string, syn []syntax.Span, emph []diffview.Span, fg, bg, emphBg string) string {
+	if t.NoSyntax || !syntaxFits(s, syn) {
+		syn = nil
+	}
+	if !spansFit(s, emph) {
+		emph = nil
+	}
+	if len(syn) == 0 && len(emph) == 0 && fg == "" && bg == "" {
+		return s
+	}
+	var b strings.Builder
+	si, ei := 0, 0
+	for pos := 0; pos < len(s); {
+		next, kind, em := len(s), syntax.Plain, false
+		for si < len(syn) && syn[si].End <= pos {
+			si++
+		}
+		if si < len(syn) {
+			if syn[si].Start <= pos {
+				kind, next = syn[si].Kind, min(next, syn[si].End)
+			} else {
+				next = min(next, syn[si].Start)
+			}
+		}
+		for ei < len(emph) && emph[ei].End <= pos {
+			ei++
+		}
+		if ei < len(emph) {
+			if emph[ei].Start <= pos {
+				em, next = true, min(next, emph[ei].End)
+			} else {
+				next = min(next, emph[ei].Start)
+			}
+		}
+		st := lipgloss.NewStyle()
+		color := fg
+		if c := t.Syntax.color(kind); c != "" && (fg == "" || bg != "") {
+			color = c
+		}
+		if color != "" {
+			st = st.Foreground(lipgloss.Color(color))
+		}
+		if back := bg; em || back != "" {
+			if em {
+				back = emphBg
+			}
+			st = st.Background(lipgloss.Color(back))
+		}
+		b.WriteString(st.Render(s[pos:next]))
+		pos = next
+	}
+	return b.String()
+}
+
+func spansFit(s string, spans []diffview.Span) bool {
+	pos := 0
+	for _, sp := range spans {
+		if sp.Start < pos || sp.End > len(s) || sp.End < sp.Start {
+			return false
+		}
+		pos = sp.End
+	}
+	return true
+}
+
+func syntaxFits(s string, spans []syntax.Span) bool {
+	pos := 0
+	for _, sp := range spans {
+		if sp.Start < pos || sp.End > len(s) || sp.End <= sp.Start {
+			return false
+		}
+		if !utf8.RuneStart(s[sp.Start]) || (sp.End < len(s) && !utf8.RuneStart(s[sp.End])) {
+			return false
+		}
+		pos = sp.End
+	}
+	return true
+}
@@ -0,0 +1,293 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+)
+
+// --- Checkpoint timeline: scrub automatic checkpoints, restore files ---
+
+const (
+	timelineLimit   = 200 // checkpoints loaded into the panel
+	timelineVisible = 10  // rows shown at once
+	// checkpointEvery throttles checkpoints when polling instead of
+	// watching, since every tick would otherwise hash the working tree.
+	checkpointEvery = 5 * time.Second
+)
+
+type checkpointMsg struct {
+	created bool
+	err     error
+}
+
+type timelineMsg struct {
+	list []gitx.Snapshot
+	err  error
+}
+
+// checkpoint records the working tree if it changed since the last
+// checkpoint. It is nil when checkpoints are off. While one is being taken,
+// further requests are folded into a single one that runs after it.
+func (m *model) checkpoint() tea.Cmd {
+	if !m.checkpoints {
+		return nil
+	}
+	if m.checkpointing {
+		m.checkpointAgain = true
+		return nil
+	}
+	m.checkpointing = true
+	return runCheckpoint(m.repoRoot)
+}
+
+func runCheckpoint(repoRoot string) tea.Cmd {
+	return func() tea.Msg {
+		_, created, err := gitx.Checkpoint(repoRoot)
+		return checkpointMsg{created: created, err: err}
+	}
+}
+
+// checkpointDue is checkpoint at most once per checkpointEvery.
+func (m *model) checkpointDue() tea.Cmd {
+	if time.Since(m.lastCheckpoint) < checkpointEvery {
+		return nil
+	}
+	m.lastCheckpoint = time.Now()
+	return m.checkpoint()
+}
+
+func loadTimeline(repoRoot string) tea.Cmd {
+	return func() tea.Msg {
+		list, err := gitx.Checkpoints(repoRoot, timelineLimit)
+		return timelineMsg{list: list, err: err}
+	}
+}
+
+func (m model) checkpointResult(msg checkpointMsg) (model, tea.Cmd) {
+	m.checkpointing = false
+	var again tea.Cmd
+	if m.checkpointAgain {
+		// the tree changed while the checkpoint was taken
+		m.checkpointAgain = false
+		again = m.checkpoint()
+	}
+	if msg.err != nil {
+		m.status = "checkpoint: " + strings.ReplaceAll(strings.TrimSpace(msg.err.Error()), "\n", " ")
+		return m, again
+	}
+	if msg.created && (m.showTimeline || m.tlBrowsing) {
+		return m, tea.Batch(loadTimeline(m.repoRoot), again)
+	}
+	return m, again
+}
+
+func (m *model) openTimeline() {
+	m.showTimeline = true
+	m.tlErr = ""
+	if !m.tlBrowsing {
+		m.tlList = nil
+		m.tlIndex, m.tlOffset, m.tlPin = 0, 0, ""
+	}
+}
+
+// timelineResult installs a reloaded list, keeping the cursor on the same
+// checkpoint. While scrubbing, the view follows a new latest checkpoint.
+func (m model) timelineResult(msg timelineMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		m.tlErr = msg.err.Error()
+		return m, m.recalcViewport()
+	}
+	cur := ""
+	if m.tlIndex < len(m.tlList) {
+		cur = m.tlList[m.tlIndex].ID
+	}
+	if msg.list == nil {
+		msg.list = []gitx.Snapshot{}
+	}
+	m.tlList, m.tlIndex = msg.list, 0
+	for i, s := range m.tlList {
+		if s.ID == cur {
+			m.tlIndex = i
+		}
+	}
+	m.scrollTimeline()
+	if m.tlBrowsing {
+		if r, ok := m.timelineRange(); ok && r != m.diffRange {
+			return m, m.previewCheckpoint()
+		}
+	}
+	return m, m.recalcViewport()
+}
+
+// timelineRange is the comparison for the cursor: the pinned checkpoint
+// against it, or it against the latest checkpoint.
+func (m model) timelineRange() (gitx.Range, bool) {
+	if m.tlIndex >= len(m.tlList) {
+		return gitx.Range{}, false
+	}
+	cur := m.tlList[m.tlIndex]
+	older, newer, label := cur, m.tlList[0], "checkpoint "+cur.Time.Format("15:04:05")+" → latest"
+	if pin, i := m.pinned(); i >= 0 {
+		older, newer = pin, cur
+		if i < m.tlIndex {
+			older, newer = cur, pin
+		}
+		label = "checkpoint " + older.Time.Format("15:04:05") + " → " + newer.Time.Format("15:04:05")
+	}
+	return gitx.Range{Base: older.ID, Head: newer.ID, Label: label}, true
+}
+
+// pinned returns the pinned checkpoint and its index, or -1.
+func (m model) pinned() (gitx.Snapshot, int) {
+	for i, s := range m.tlList {
+		if s.ID == m.tlPin {
+			return s, i
+		}
+	}
+	return gitx.Snapshot{}, -1
+}
+
+// previewCheckpoint shows the comparison for the cursor in the main panes.
+func (m *model) previewCheckpoint() tea.Cmd {
+	r, ok := m.timelineRange()
+	if !ok {
+		return nil
+	}
+	if !m.tlBrowsing {
+		m.tlPrevRange = m.diffRange
+		m.tlBrowsing = true
+	}
+	return m.previewRange(r)
+}
+
+// leaveTimeline returns to what was shown before scrubbing.
+func (m *model) leaveTimeline() tea.Cmd {
+	m.tlBrowsing = false
+	m.status = ""
+	return m.switchRange(m.tlPrevRange)
+}
+
+func (m *model) scrollTimeline() {
+	if m.tlIndex < m.tlOffset {
+		m.tlOffset = m.tlIndex
+	} else if m.tlIndex >= m.tlOffset+timelineVisible {
+		m.tlOffset = m.tlIndex - timelineVisible + 1
+	}
+}
+
+func (m model) handleTimelineKeys(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.String() {
+	case "esc", "q":
+		m.showTimeline = false
+		if m.tlBrowsing {
+			return m, m.leaveTimeline()
+		}
+		return m, m.recalcViewport()
+	case "enter", "T":
+		// keep the comparison and browse its files
+		m.showTimeline = false
+		return m, m.recalcViewport()
+	case "j", "down":
+		if m.tlIndex < len(m.tlList)-1 {
+			m.tlIndex++
+		}
+	case "k", "up":
+		if m.tlIndex > 0 {
+			m.tlIndex--
+		}
+	case "g":
+		m.tlIndex = 0
+	case "G":
+		m.tlIndex = max(len(m.tlList)-1, 0)
+	case " ":
+		if m.tlIndex >= len(m.tlList) {
+			return m, nil
+		}
+		if id := m.tlList[m.tlIndex].ID; m.tlPin == id {
+			m.tlPin = ""
+		} else {
+			m.tlPin = id
+		}
+	case "r":
+		return m, m.restoreFromCheckpoint()
+	default:
+		return m, nil
+	}
+	m.scrollTimeline()
+	return m, m.previewCheckpoint()
+}
+
+// restoreFromCheckpoint puts the selected file back as it was in the
+// checkpoint under the cursor. The current state is checkpointed first, so
+// the restore itself can be undone from the timeline.
+func (m *model) restoreFromCheckpoint() tea.Cmd {
+	if m.tlIndex >= len(m.tlList) || len(m.files) == 0 {
+		return nil
+	}
+	cp := m.tlList[m.tlIndex]
+	path := m.files[m.selected].Path
+	repoRoot := m.repoRoot
+	return func() tea.Msg {
+		if _, _, err := gitx.Checkpoint(repoRoot); err != nil {
+			return patchResultMsg{err: err}
+		}
+		err := gitx.RestoreFile(repoRoot, cp.ID, path)
+		return patchResultMsg{done: "restored " + path + " to checkpoint " + cp.Time.Format("15:04:05"), err: err}
+	}
+}
+
+func (m model) timelineOverlayLines(width int) []string {
+	if !m.showTimeline {
+		return nil
+	}
+	lines := make([]string, 0, timelineVisible+4)
+	lines = append(lines, strings.Repeat("─", width))
+	title := lipgloss.NewStyle().Bold(true).Render("Timeline — j/k: scrub, space: pin base, enter: browse files, esc: back")
+	lines = append(lines, title)
+	faint := lipgloss.NewStyle().Faint(true)
+	if m.tlErr != "" {
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("Error: ")+m.tlErr)
+		return lines
+	}
+	if m.tlList == nil {
+		lines = append(lines, faint.Render("Loading checkpoints…"))
+		return lines
+	}
+	if len(m.tlList) == 0 {
+		if !m.checkpoints {
+			lines = append(lines, faint.Render("No checkpoints (checkpoints are off)"))
+		} else {
+			lines = append(lines, faint.Render("No checkpoints yet"))
+		}
+		return lines
+	}
+	hash := lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
+	pin := lipgloss.NewStyle().Foreground(lipgloss.Color("63"))
+	end := min(m.tlOffset+timelineVisible, len(m.tlList))
+	for i := m.tlOffset; i < end; i++ {
+		s := m.tlList[i]
+		cur := "  "
+		if i == m.tlIndex {
+			cur = "> "
+		}
+		line := fmt.Sprintf("%s%s %s", cur, hash.Render(s.ID[:7]), s.Time.Format("2006-01-02 15:04:05"))
+		if i == 0 {
+			line += faint.Render("  latest")
+		}
+		if s.ID == m.tlPin {
+			line += pin.Render("  [base]")
+		}
+		lines = append(lines, line)
+	}
+	footer := fmt.Sprintf("%d/%d", m.tlIndex+1, len(m.tlList))
+	if len(m.files) > 0 {
+		footer += "  r: restore " + m.files[m.selected].Path + " to this checkpoint"
+	}
+	lines = append(lines, faint.Render(footer))
+	return lines
+}
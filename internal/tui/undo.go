@@ -0,0 +1,144 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/interpretive-systems/diffium/internal/gitx"
+)
+
+// --- Undo confirmation ---
+
+type undoPreviewMsg struct {
+	op  gitx.UndoOp
+	ok  bool // false when there is nothing to undo
+	err error
+}
+
+type undoResultMsg struct {
+	op  gitx.UndoOp
+	err error
+}
+
+// openUndo shows the operation U would reverse and asks for two
+// confirmations, as the reset wizard does.
+func (m *model) openUndo() tea.Cmd {
+	m.showUndo = true
+	m.udStep = 0
+	m.udOp = gitx.UndoOp{}
+	m.udLoaded = false
+	m.udRunning = false
+	m.udErr = ""
+	return tea.Batch(loadLastUndo(m.repoRoot), m.recalcViewport())
+}
+
+func loadLastUndo(repoRoot string) tea.Cmd {
+	return func() tea.Msg {
+		op, ok, err := gitx.LastUndo(repoRoot)
+		return undoPreviewMsg{op: op, ok: ok, err: err}
+	}
+}
+
+// runUndo reverses the last reset/clean, uncommit or checkout.
+func runUndo(repoRoot string, checkpoint bool) tea.Cmd {
+	return func() tea.Msg {
+		op, err := gitx.Undo(repoRoot, checkpoint)
+		return undoResultMsg{op: op, err: err}
+	}
+}
+
+func (m model) undoPreviewResult(msg undoPreviewMsg) (model, tea.Cmd) {
+	m.udLoaded = true
+	if msg.err != nil {
+		m.udErr = strings.ReplaceAll(strings.TrimSpace(msg.err.Error()), "\n", " ")
+	} else if msg.ok {
+		m.udOp = msg.op
+	}
+	return m, m.recalcViewport()
+}
+
+func (m model) undoResult(msg undoResultMsg) (model, tea.Cmd) {
+	m.udRunning = false
+	if msg.err != nil {
+		m.udErr = strings.ReplaceAll(strings.TrimSpace(msg.err.Error()), "\n", " ")
+		return m, tea.Batch(m.reloadFiles(), m.recalcViewport())
+	}
+	m.udErr = ""
+	m.showUndo = false
+	m.status = "undid " + msg.op.String()
+	return m, tea.Batch(m.reloadFiles(), loadLastCommit(m.repoRoot), loadCurrentBranch(m.repoRoot), m.recalcViewport())
+}
+
+func (m model) handleUndoKeys(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch m.udStep {
+	case 0:
+		switch key.String() {
+		case "esc", "q":
+			m.showUndo = false
+			return m, m.recalcViewport()
+		case "enter":
+			if m.udOp.Kind != "" {
+				m.udStep = 1
+				return m, m.recalcViewport()
+			}
+		}
+	case 1:
+		switch key.String() {
+		case "esc":
+			if !m.udRunning {
+				m.showUndo = false
+				return m, m.recalcViewport()
+			}
+		case "b":
+			if !m.udRunning {
+				m.udStep = 0
+				m.udErr = ""
+				return m, m.recalcViewport()
+			}
+		case "y", "enter":
+			if !m.udRunning {
+				m.udRunning = true
+				m.udErr = ""
+				return m, tea.Batch(runUndo(m.repoRoot, !m.noCheckpoints), m.recalcViewport())
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m model) undoOverlayLines(width int) []string {
+	if !m.showUndo {
+		return nil
+	}
+	lines := make([]string, 0, 6)
+	lines = append(lines, strings.Repeat("─", width))
+	faint := lipgloss.NewStyle().Faint(true)
+	errLine := func() {
+		if m.udErr != "" {
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("Error: ")+m.udErr)
+		}
+	}
+	switch m.udStep {
+	case 0: // first (yellow) confirmation naming the operation
+		title := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("220")).Render("Undo — Restores the state saved before the last operation (enter: continue, esc: cancel)")
+		lines = append(lines, title)
+		switch {
+		case !m.udLoaded:
+			lines = append(lines, faint.Render("Loading…"))
+		case m.udOp.Kind != "":
+			lines = append(lines, "Operation: "+m.udOp.String()+faint.Render("  "+m.udOp.Time.Format("2006-01-02 15:04:05")))
+		case m.udErr == "":
+			lines = append(lines, faint.Render("Nothing to undo"))
+		}
+		errLine()
+	case 1: // final (red) confirmation
+		title := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("196")).Render("FINAL CONFIRMATION — Undo " + m.udOp.String() + " (y/enter: execute, b: back, esc: cancel)")
+		lines = append(lines, title)
+		if m.udRunning {
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Render("Undoing…"))
+		}
+		errLine()
+	}
+	return lines
+}
@@ -0,0 +1,93 @@
+//go:build linux
+
+package watch
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY |
+	syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_ATTRIB | syscall.IN_DELETE_SELF | syscall.IN_ONLYDIR
+
+// inotify is the Linux backend.
+type inotify struct {
+	fd   int
+	file *os.File // wraps fd so reads go through the runtime poller
+
+	mu   sync.Mutex
+	dirs map[int32]string // watch descriptor -> directory
+}
+
+func newBackend(w *Watcher) (backend, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	in := &inotify{fd: fd, file: os.NewFile(uintptr(fd), "inotify"), dirs: map[int32]string{}}
+	go in.run(w)
+	return in, nil
+}
+
+func (in *inotify) add(dir string) error {
+	wd, err := syscall.InotifyAddWatch(in.fd, dir, inotifyMask)
+	if err != nil {
+		return err
+	}
+	in.mu.Lock()
+	in.dirs[int32(wd)] = dir
+	in.mu.Unlock()
+	return nil
+}
+
+func (in *inotify) close() error {
+	return in.file.Close()
+}
+
+func (in *inotify) run(w *Watcher) {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := in.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				w.fail()
+			}
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameBytes := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(ev.Len)]
+			off += syscall.SizeofInotifyEvent + int(ev.Len)
+
+			if ev.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				// Events were dropped; report a change so the UI rescans.
+				w.notify(w.repoRoot, "", false)
+				continue
+			}
+			in.mu.Lock()
+			dir, ok := in.dirs[ev.Wd]
+			if ev.Mask&syscall.IN_IGNORED != 0 {
+				delete(in.dirs, ev.Wd)
+			}
+			in.mu.Unlock()
+			if !ok || ev.Mask&syscall.IN_IGNORED != 0 {
+				continue
+			}
+			name := cString(nameBytes)
+			w.notify(dir, name, ev.Mask&syscall.IN_ISDIR != 0 && ev.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0)
+		}
+	}
+}
+
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package watch
+
+func newBackend(*Watcher) (backend, error) {
+	return nil, ErrUnsupported
+}
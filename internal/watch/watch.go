@@ -0,0 +1,262 @@
+// Package watch reports changes to a git working tree using filesystem
+// notifications, so the UI only refreshes when something actually changed.
+package watch
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnsupported is returned by New on platforms without a native backend.
+// Callers are expected to fall back to polling.
+var ErrUnsupported = errors.New("filesystem watching not supported on this platform")
+
+const (
+	// quietPeriod is how long the tree must be idle before a burst of
+	// events is reported.
+	quietPeriod = 150 * time.Millisecond
+	// maxDelay bounds how long a continuous burst (e.g. an agent writing
+	// many files) can postpone a refresh.
+	maxDelay = time.Second
+)
+
+// gitDirFiles are the entries in the git dir whose changes affect status:
+// the index, the checked-out ref and anything that moves HEAD.
+var gitDirFiles = map[string]bool{
+	"index":       true,
+	"HEAD":        true,
+	"packed-refs": true,
+	"MERGE_HEAD":  true,
+	"ORIG_HEAD":   true,
+}
+
+// Watcher delivers a value on Events once changes settle.
+type Watcher struct {
+	// Events receives one value per settled burst of changes. It is closed
+	// when the watcher stops, including on backend errors.
+	Events <-chan struct{}
+
+	repoRoot string
+	gitDir   string
+	events   chan struct{}
+	backend  backend
+
+	mu      sync.Mutex
+	pending []string // working tree paths changed in the current burst
+	gitHit  bool     // the current burst touched the git dir
+	timer   *time.Timer
+	first   time.Time
+	closed  bool
+}
+
+// backend is the platform-specific notification source.
+type backend interface {
+	// add starts watching a single directory (non-recursive).
+	add(dir string) error
+	// close stops the backend and unblocks run.
+	close() error
+}
+
+// New starts watching repoRoot recursively (skipping ignored directories)
+// along with the repository's git dir.
+func New(repoRoot string) (*Watcher, error) {
+	gitDir, err := absoluteGitDir(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		repoRoot: repoRoot,
+		gitDir:   gitDir,
+		events:   make(chan struct{}, 1),
+	}
+	w.Events = w.events
+	b, err := newBackend(w)
+	if err != nil {
+		return nil, err
+	}
+	w.backend = b
+	ignored := ignoredDirs(repoRoot)
+	if err := w.addTree(repoRoot, ignored); err != nil {
+		_ = b.close()
+		return nil, err
+	}
+	for _, d := range []string{gitDir, filepath.Join(gitDir, "logs")} {
+		if err := b.add(d); err != nil && d == gitDir {
+			_ = b.close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Close stops the watcher and closes Events.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	close(w.events)
+	w.mu.Unlock()
+	return w.backend.close()
+}
+
+// addTree watches dir and all its subdirectories, skipping .git and any
+// directory in ignored (absolute paths).
+func (w *Watcher) addTree(dir string, ignored map[string]bool) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Directories can vanish while walking; skip them.
+			if p == dir {
+				return err
+			}
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" || ignored[p] {
+			return filepath.SkipDir
+		}
+		if err := w.backend.add(p); err != nil && p == dir {
+			return err
+		}
+		return nil
+	})
+}
+
+// notify is called by the backend for each event. dir is the watched
+// directory, name the entry within it; isDir reports a created directory.
+func (w *Watcher) notify(dir, name string, isDir bool) {
+	full := filepath.Join(dir, name)
+	inGit := dir == w.gitDir || strings.HasPrefix(dir, w.gitDir+string(filepath.Separator))
+	if inGit {
+		if !gitDirFiles[name] {
+			return
+		}
+	} else if isDir && name != ".git" {
+		// New directory: watch it too unless it is ignored.
+		if rel, err := filepath.Rel(w.repoRoot, full); err == nil && !allIgnored(w.repoRoot, []string{rel + "/"}) {
+			_ = w.addTree(full, nil)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	if inGit {
+		w.gitHit = true
+	} else if rel, err := filepath.Rel(w.repoRoot, full); err == nil {
+		w.pending = append(w.pending, rel)
+	}
+	now := time.Now()
+	if w.timer == nil {
+		w.first = now
+		w.timer = time.AfterFunc(quietPeriod, w.flush)
+		return
+	}
+	// Debounce: push the deadline out, but never past maxDelay from the
+	// first event of the burst.
+	wait := quietPeriod
+	if rest := maxDelay - now.Sub(w.first); rest < wait {
+		wait = rest
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	w.timer.Reset(wait)
+}
+
+// flush reports the current burst unless it only touched ignored files.
+func (w *Watcher) flush() {
+	w.mu.Lock()
+	paths := w.pending
+	gitHit := w.gitHit
+	w.pending = nil
+	w.gitHit = false
+	w.timer = nil
+	w.mu.Unlock()
+	if !gitHit && allIgnored(w.repoRoot, paths) {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	select {
+	case w.events <- struct{}{}:
+	default: // a refresh is already pending
+	}
+}
+
+// fail closes the watcher after an unrecoverable backend error so callers
+// can fall back to polling.
+func (w *Watcher) fail() {
+	_ = w.Close()
+}
+
+func absoluteGitDir(repoRoot string) (string, error) {
+	out, err := exec.Command("git", "-C", repoRoot, "rev-parse", "--absolute-git-dir").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ignoredDirs lists ignored directories (absolute, without trailing slash)
+// so they are never watched.
+func ignoredDirs(repoRoot string) map[string]bool {
+	out, err := exec.Command("git", "-C", repoRoot, "ls-files", "--others", "--ignored", "--exclude-standard", "--directory", "-z").Output()
+	dirs := map[string]bool{}
+	if err != nil {
+		return dirs
+	}
+	for _, p := range strings.Split(string(out), "\x00") {
+		if strings.HasSuffix(p, "/") {
+			dirs[filepath.Join(repoRoot, filepath.FromSlash(strings.TrimSuffix(p, "/")))] = true
+		}
+	}
+	return dirs
+}
+
+// allIgnored reports whether every path is excluded by .gitignore rules.
+// Tracked files are never considered ignored.
+func allIgnored(repoRoot string, paths []string) bool {
+	seen := make(map[string]bool, len(paths))
+	var in bytes.Buffer
+	for _, p := range paths {
+		p = filepath.ToSlash(p)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		in.WriteString(p)
+		in.WriteByte(0)
+	}
+	if len(seen) == 0 {
+		return true
+	}
+	cmd := exec.Command("git", "-C", repoRoot, "check-ignore", "--stdin", "-z")
+	cmd.Stdin = &in
+	out, _ := cmd.Output() // exits 1 when nothing is ignored
+	n := 0
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p != "" {
+			n++
+		}
+	}
+	return n >= len(seen)
+}
@@ -0,0 +1,90 @@
+package watch
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher_ReportsChangesAndSkipsIgnored(t *testing.T) {
+	dir := t.TempDir()
+	mustRun(t, dir, "git", "init", "-q")
+	mustRun(t, dir, "git", "config", "user.email", "test@example.com")
+	mustRun(t, dir, "git", "config", "user.name", "Test User")
+	write(t, filepath.Join(dir, ".gitignore"), "*.log\nbuild/\n")
+	write(t, filepath.Join(dir, "f.txt"), "one\n")
+	if err := os.Mkdir(filepath.Join(dir, "build"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, dir, "git", "add", ".")
+	mustRun(t, dir, "git", "commit", "-q", "-m", "init")
+
+	w, err := New(dir)
+	if errors.Is(err, ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// Ignored files (and files in ignored directories) do not trigger.
+	write(t, filepath.Join(dir, "debug.log"), "noise\n")
+	write(t, filepath.Join(dir, "build", "out.o"), "noise\n")
+	expectNoEvent(t, w)
+
+	// A tracked file change does, even when written in several bursts.
+	for i := 0; i < 5; i++ {
+		write(t, filepath.Join(dir, "f.txt"), "two\n")
+	}
+	expectEvent(t, w)
+
+	// New directories are watched as they appear.
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, w)
+	write(t, filepath.Join(dir, "sub", "new.txt"), "x\n")
+	expectEvent(t, w)
+
+	// Staging touches only .git/index.
+	mustRun(t, dir, "git", "add", "f.txt")
+	expectEvent(t, w)
+}
+
+func expectEvent(t *testing.T, w *Watcher) {
+	t.Helper()
+	select {
+	case <-w.Events:
+	case <-time.After(3 * time.Second):
+		t.Fatal("expected a change event")
+	}
+}
+
+func expectNoEvent(t *testing.T, w *Watcher) {
+	t.Helper()
+	select {
+	case <-w.Events:
+		t.Fatal("unexpected change event")
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func mustRun(t *testing.T, dir string, name string, args ...string) {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("command %s %v failed: %v\n%s", name, args, err, out)
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
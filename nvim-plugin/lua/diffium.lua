@@ -11,15 +11,17 @@ local function find_diffium()
   return path
 end
 
--- Create floating terminal for Diffium
-local function open_floating_term(cmd, opts)
-  local buf = vim.api.nvim_create_buf(false, true)
+-- The running Diffium terminal: its buffer, floating window, and the window
+-- that was current when it was opened (where files are edited)
+M.term = {}
+
+local function open_float(buf)
   local width = math.floor(vim.o.columns * 0.9)
   local height = math.floor(vim.o.lines * 0.9)
   local row = math.floor((vim.o.lines - height) / 2)
   local col = math.floor((vim.o.columns - width) / 2)
 
-  local ok, win = pcall(vim.api.nvim_open_win, buf, true, {
+  return pcall(vim.api.nvim_open_win, buf, true, {
     relative = "editor",
     width = width,
     height = height,
@@ -28,6 +30,13 @@ local function open_floating_term(cmd, opts)
     style = "minimal",
     border = "rounded",
   })
+end
+
+-- Create floating terminal for Diffium
+local function open_floating_term(cmd, opts)
+  local prev_win = vim.api.nvim_get_current_win()
+  local buf = vim.api.nvim_create_buf(false, true)
+  local ok, win = open_float(buf)
 
   if not ok then
     vim.cmd("terminal " .. cmd)
@@ -35,12 +44,16 @@ local function open_floating_term(cmd, opts)
     return
   end
 
-  vim.fn.termopen(cmd, {
+  M.term = { buf = buf, win = win, prev_win = prev_win }
+  M.term.job = vim.fn.termopen(cmd, {
     on_exit = function(_, code)
       vim.schedule(function()
         if code ~= 0 then
           vim.notify(string.format("[Diffium.nvim] exited with code %d", code), vim.log.levels.WARN)
         end
+        if M.term.buf == buf then
+          M.term = {}
+        end
         if opts.auto_close ~= false then
           if vim.api.nvim_buf_is_valid(buf) then
             vim.api.nvim_buf_delete(buf, { force = true })
@@ -57,12 +70,82 @@ local function open_floating_term(cmd, opts)
   vim.cmd("startinsert")
 end
 
+-- A window to edit files in: the one Diffium was opened from, else the
+-- first non-floating window of the tab
+local function edit_window()
+  local prev = M.term.prev_win
+  if prev and vim.api.nvim_win_is_valid(prev) then
+    return prev
+  end
+  for _, win in ipairs(vim.api.nvim_tabpage_list_wins(0)) do
+    if vim.api.nvim_win_get_config(win).relative == "" then
+      return win
+    end
+  end
+  return nil
+end
+
+--- Open a file at a line, as requested by Diffium's "e" key.
+-- Hides the floating terminal unless close_on_open is false; :Diffium
+-- shows it again while Diffium is still running.
+-- @param path string Absolute path
+-- @param line number 1-based line, or 0
+function M.open_file(path, line)
+  vim.schedule(function()
+    local opts = M.opts or {}
+    local float = M.term.win
+    local keep = opts.close_on_open == false and float and vim.api.nvim_win_is_valid(float)
+    vim.cmd("stopinsert")
+    if float and vim.api.nvim_win_is_valid(float) and not keep then
+      vim.api.nvim_win_close(float, false)
+      M.term.win = nil
+    end
+    local win = edit_window()
+    if win then
+      vim.api.nvim_set_current_win(win)
+    end
+    vim.cmd("edit " .. vim.fn.fnameescape(path))
+    if line and line > 0 then
+      pcall(vim.api.nvim_win_set_cursor, 0, { line, 0 })
+      vim.cmd("normal! zz")
+    end
+    if keep then
+      vim.api.nvim_set_current_win(float)
+      vim.cmd("startinsert")
+    end
+  end)
+end
+
+-- Show the hidden terminal of a Diffium that is still running
+local function reopen_term()
+  local buf = M.term.buf
+  if not (buf and vim.api.nvim_buf_is_valid(buf)) then
+    return false
+  end
+  if M.term.win and vim.api.nvim_win_is_valid(M.term.win) then
+    vim.api.nvim_set_current_win(M.term.win)
+  else
+    M.term.prev_win = vim.api.nvim_get_current_win()
+    local ok, win = open_float(buf)
+    if not ok then
+      return false
+    end
+    M.term.win = win
+  end
+  vim.cmd("startinsert")
+  return true
+end
+
 --- Public entrypoint: open Diffium inside Neovim
 -- @param args table|nil Optional CLI args for Diffium
 function M.open(args)
   local diffium = find_diffium()
   if not diffium then return end
 
+  if not (args and #args > 0) and reopen_term() then
+    return
+  end
+
   local cmd = { diffium }
   if args and #args > 0 then
     vim.list_extend(cmd, args)
@@ -74,8 +157,49 @@ function M.open(args)
   open_floating_term(joined_cmd, M.opts or {})
 end
 
+-- Run git and return the first line of its output, or nil on failure
+local function git_line(args)
+  local out = vim.fn.systemlist(vim.list_extend({ "git" }, args))
+  if vim.v.shell_error ~= 0 or not out[1] or out[1] == "" then
+    return nil
+  end
+  return out[1]
+end
+
+--- Load the findings exported with Diffium's "F" key into the quickfix list
+function M.load_findings()
+  local root = git_line({ "rev-parse", "--show-toplevel" })
+  if not root then
+    vim.notify("[Diffium.nvim] not in a git repository.", vim.log.levels.ERROR)
+    return
+  end
+  local path = git_line({ "-C", root, "rev-parse", "--git-path", "diffium/findings.txt" })
+  if path and not path:match("^/") and not path:match("^%a:[/\\]") then
+    path = root .. "/" .. path
+  end
+  if not path or vim.fn.filereadable(path) == 0 then
+    vim.notify("[Diffium.nvim] no findings; press F in Diffium to export them.", vim.log.levels.WARN)
+    return
+  end
+
+  local items = {}
+  for _, l in ipairs(vim.fn.readfile(path)) do
+    local file, lnum, col, text = l:match("^(.-):(%d+):(%d+): (.*)$")
+    if file then
+      table.insert(items, {
+        filename = root .. "/" .. file,
+        lnum = tonumber(lnum),
+        col = tonumber(col),
+        text = text,
+      })
+    end
+  end
+  vim.fn.setqflist({}, " ", { title = "Diffium findings", items = items })
+  vim.cmd("copen")
+end
+
 --- Setup function for configuration
--- @param opts table { command="Diffium", keymap="<C-d>", auto_close=true }
+-- @param opts table { command="Diffium", keymap="<C-d>", auto_close=true, close_on_open=true }
 function M.setup(opts)
   M.opts = opts or {}
   local cmd_name = M.opts.command or "Diffium"
@@ -84,6 +208,7 @@ function M.setup(opts)
   vim.api.nvim_create_user_command(cmd_name, function(params)
     M.open(params.fargs)
   end, { nargs = "*" })
+  vim.api.nvim_create_user_command(cmd_name .. "Findings", M.load_findings, {})
 
   if keymap and keymap ~= "" then
     vim.keymap.set("n", keymap, string.format(":%s<CR>", cmd_name),